@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/moore0n/hlstail/pkg/render"
+	"github.com/moore0n/hlstail/pkg/term"
+	"github.com/urfave/cli/v2"
+)
+
+// compareNames are what the two playlists are called in the output.
+var compareNames = [2]string{"primary", "backup"}
+
+// compareCommand tails two playlists of the same stream and compares them.
+func compareCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "compare",
+		Usage:     "Tail a primary and backup playlist of the same stream, or the stream from two CDNs, and show where they differ",
+		ArgsUsage: "<primary> <backup>",
+		Flags: append(append(variantFlags(),
+			&cli.IntFlag{
+				Name:  "interval",
+				Usage: "The number of seconds to wait between updates",
+				Value: 3,
+			},
+			&cli.IntFlag{
+				Name:  "count",
+				Usage: "The number of media sequences to display, 0 fits as many as the terminal has room for",
+			},
+			keymapFlag(),
+		), harFlags()...),
+		Action: func(c *cli.Context) error {
+			primary, backup := c.Args().Get(0), c.Args().Get(1)
+
+			if primary == "" || backup == "" {
+				cli.ShowCommandHelpAndExit(c, "compare", 0)
+			}
+
+			client, saveHAR := newClient(c)
+			fetcher := hls.NewFetcher(client)
+
+			err := runCompare([2]string{primary, backup}, fetcher, c)
+
+			if harErr := saveHAR(); err == nil {
+				err = harErr
+			}
+
+			return err
+		},
+	}
+}
+
+// runCompare reloads both playlists together until the user quits or hlstail
+// is asked to stop.
+func runCompare(playlists [2]string, fetcher hls.Fetcher, c *cli.Context) error {
+	interval := time.Duration(c.Int("interval")) * time.Second
+
+	if interval <= 0 {
+		return errors.New("--interval must be at least 1 second")
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	var sessions [2]*hls.Session
+
+	for i, playlist := range playlists {
+		sess, err := hls.NewSession(playlist, fetcher)
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", compareNames[i], err)
+		}
+
+		// The variant is chosen in each playlist, their ladders may not be
+		// in the same order.
+		variant, err := selectVariant(c, sess.Master)
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", compareNames[i], err)
+		}
+
+		sess.SetVariant(variant)
+		sessions[i] = sess
+	}
+
+	if !term.IsTerminal(os.Stdout) || !term.IsTerminal(os.Stdin) {
+		text := render.NewCompareText(os.Stdout)
+
+		return compareLoop(ctx, sessions, interval, nil, nil, func(a *hls.Update, b *hls.Update) {
+			text.Comparison(a, b, compareNames)
+		})
+	}
+
+	keymap, err := newKeymap(c.String("keymap"))
+
+	if err != nil {
+		return err
+	}
+
+	termSess := term.NewSession()
+
+	if err := termSess.MakeRaw(); err != nil {
+		return err
+	}
+
+	screen := render.NewCompareScreen(termSess, c.Int("count"))
+	screen.Start()
+	defer screen.End()
+
+	return compareLoop(ctx, sessions, interval, termSess.ReadKeys(ctx), keymap, func(a *hls.Update, b *hls.Update) {
+		screen.Draw(a, b, compareNames)
+	})
+}
+
+// compareLoop reloads both sessions at the same time every interval and
+// passes the updates to draw.
+func compareLoop(ctx context.Context, sessions [2]*hls.Session, interval time.Duration, keys <-chan term.Key, keymap *term.Keymap, draw func(a *hls.Update, b *hls.Update)) error {
+	if interval <= 0 {
+		return fmt.Errorf("the interval must be more than 0, not %s", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var updates [2]*hls.Update
+		var wg sync.WaitGroup
+
+		for i, sess := range sessions {
+			wg.Add(1)
+
+			go func(i int, sess *hls.Session) {
+				defer wg.Done()
+				updates[i] = sess.Reload(ctx)
+			}(i, sess)
+		}
+
+		wg.Wait()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		draw(updates[0], updates[1])
+
+		redraw := func() { draw(updates[0], updates[1]) }
+
+		if !waitForTick(ctx, ticker, keys, keymap, redraw) {
+			return nil
+		}
+	}
+}
+
+// waitForTick waits for the next tick, calling redraw if the terminal is
+// resized. It returns false if the user quit or ctx was cancelled first.
+func waitForTick(ctx context.Context, ticker *time.Ticker, keys <-chan term.Key, keymap *term.Keymap, redraw func()) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case key, ok := <-keys:
+			if !ok || keymap.Is(key, actionQuit) {
+				return false
+			}
+
+			if key.Name == term.KeyResize {
+				redraw()
+			}
+		case <-ticker.C:
+			return true
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// newCompareSessions creates two sessions of the first variant of the test
+// master playlist.
+func newCompareSessions(t *testing.T) [2]*hls.Session {
+	t.Helper()
+
+	var sessions [2]*hls.Session
+
+	for i := range sessions {
+		sess, err := hls.NewSession(testMasterURL, newFakeFetcher())
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sess.SetVariant(0)
+		sessions[i] = sess
+	}
+
+	return sessions
+}
+
+func TestCompareLoopRejectsInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		draw := func(a *hls.Update, b *hls.Update) {
+			t.Errorf("drew with an interval of %s", interval)
+		}
+
+		if err := compareLoop(context.Background(), newCompareSessions(t), interval, nil, nil, draw); err == nil {
+			t.Errorf("compareLoop with an interval of %s succeeded, want an error", interval)
+		}
+	}
+}
+
+func TestCompareLoopReloadsBoth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	draws := 0
+
+	draw := func(a *hls.Update, b *hls.Update) {
+		draws++
+
+		if a.Err != nil || b.Err != nil {
+			t.Errorf("reload failed: %v, %v", a.Err, b.Err)
+		}
+
+		if got := a.Playlist.LastSegment().Sequence; got != draws {
+			t.Errorf("draw %d showed sequence %d", draws, got)
+		}
+
+		if draws == 3 {
+			cancel()
+		}
+	}
+
+	sessions := newCompareSessions(t)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- compareLoop(ctx, sessions, time.Millisecond, nil, nil, draw)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("compareLoop failed: %s", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("compareLoop did not return")
+	}
+
+	if draws != 3 {
+		t.Errorf("drew %d times, want 3", draws)
+	}
+}
@@ -0,0 +1,650 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/moore0n/hlstail/pkg/render"
+	"github.com/moore0n/hlstail/pkg/term"
+)
+
+// command is an action requested by the user while tailing a variant.
+type command int
+
+const (
+	cmdPause command = iota
+	cmdResume
+	cmdStep
+	cmdDiff
+	cmdChangeVariant
+	cmdQuit
+	cmdPageUp
+	cmdPageDown
+	cmdFirst
+	cmdLast
+	cmdSearch
+)
+
+// allPages scrolls through the whole history at once.
+const allPages = 1 << 20
+
+// masterInterval is how often the master playlist is refreshed to pick the
+// variant again while tailing one picked by a Selector.
+const masterInterval = 30 * time.Second
+
+// clock decides when the update loop reloads next.
+type clock interface {
+	// next returns how long to wait before reloading after updates, one for
+	// each variant reloaded together, or false once there is nothing left to
+	// reload.
+	next(updates []*hls.Update) (time.Duration, bool)
+}
+
+// intervalClock reloads at a fixed interval.
+type intervalClock time.Duration
+
+func (c intervalClock) next(updates []*hls.Update) (time.Duration, bool) {
+	return time.Duration(c), true
+}
+
+// controller coordinates user input and variant updates for a tail session.
+// It is the only goroutine that draws, the update loop hands it each reload
+// over a channel.
+type controller struct {
+	hls       *hls.Session
+	renderer  render.Renderer
+	keys      <-chan term.Key
+	keymap    *term.Keymap
+	clock     clock
+	paused    bool
+	observers []func(*hls.Update)
+	picker    *render.Picker
+
+	// masterInterval is how often the master is refreshed for a Selector,
+	// notice is the problem last shown to the user.
+	masterInterval time.Duration
+	notice         string
+}
+
+// newController creates a controller reading user input from keys, which are
+// bound to actions by keymap. A nil keys channel runs the session without any
+// user input.
+func newController(sess *hls.Session, renderer render.Renderer, keys <-chan term.Key, keymap *term.Keymap, clk clock) *controller {
+	return &controller{
+		hls:      sess,
+		renderer: renderer,
+		keys:     keys,
+		keymap:   keymap,
+		clock:    clk,
+
+		masterInterval: masterInterval,
+	}
+}
+
+// startPaused pauses every variant after its first reload until the user
+// resumes or steps through the reloads.
+func (c *controller) startPaused() {
+	c.paused = true
+}
+
+// observe calls fn with every reload, whether or not it is shown. It is called
+// from the update loop.
+func (c *controller) observe(fn func(*hls.Update)) {
+	c.observers = append(c.observers, fn)
+}
+
+// run drives the session until the user quits or ctx is cancelled. The
+// variants are the indexes to tail, side by side when there is more than one.
+// No variants tails the variant the session's Selector picked, or prompts the
+// user to select them, or uses the first when there is no user input.
+func (c *controller) run(ctx context.Context, variants []int) error {
+	for {
+		if c.hls.Selector != nil {
+			if !c.tailVariant(ctx) {
+				return nil
+			}
+
+			// The user picks by hand from now on.
+			c.hls.Selector = nil
+			continue
+		}
+
+		if len(variants) == 0 {
+			variants = []int{0}
+
+			if c.keys != nil {
+				selected, ok := c.pollForVariant(ctx)
+
+				if !ok {
+					return nil
+				}
+
+				variants = selected
+			}
+		}
+
+		// Set the variants that were selected in the previous loop.
+		c.hls.SetVariants(variants)
+
+		if !c.tailVariant(ctx) {
+			return nil
+		}
+
+		// Reset the variants so that we prompt for variant selection again.
+		variants = nil
+	}
+}
+
+// tailVariant runs the update loop for the selected variants. It returns true
+// when the user asked to change variant and false when the session is over.
+func (c *controller) tailVariant(ctx context.Context) bool {
+	ctx, cancel := context.WithCancel(ctx)
+
+	commands := make(chan command)
+	updates := make(chan []*hls.Update)
+	notices := make(chan string)
+	done := make(chan struct{})
+
+	// A problem from the variant picker no longer applies.
+	c.notify("")
+
+	go func() {
+		defer close(done)
+		c.updateLoop(ctx, commands, updates, notices)
+	}()
+
+	// Stop the update loop and wait for it so that only one ever runs.
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Commands are queued so that input is never blocked by a pending update.
+	var queue []command
+
+	paused := c.paused
+	diff := false
+
+	// The history and the diff are of a single variant, panes have neither.
+	panes := len(c.hls.Variants) > 1
+
+	// While searching keys are typed into the query rather than run.
+	searching := false
+	var query, lastQuery string
+
+	for {
+		var out chan<- command
+		var next command
+
+		if len(queue) > 0 {
+			out = commands
+			next = queue[0]
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-done:
+			// There is nothing left to reload.
+			return false
+		case out <- next:
+			queue = queue[1:]
+		case us := <-updates:
+			if len(us) == 1 {
+				c.renderer.Update(us[0])
+			} else {
+				c.renderer.Panes(us)
+			}
+
+			if paused {
+				c.renderer.Paused()
+			}
+		case notice := <-notices:
+			c.notify(notice)
+		case key, ok := <-c.keys:
+			if !ok {
+				return false
+			}
+
+			if key.Name == term.KeyResize {
+				c.renderer.Resize()
+				continue
+			}
+
+			if searching {
+				switch key.Name {
+				case "enter":
+					// An empty search repeats the last one further back.
+					if query == "" {
+						query = lastQuery
+					}
+
+					searching = false
+					c.renderer.Prompt("")
+
+					if query != "" {
+						lastQuery = query
+						c.renderer.Search(query)
+					}
+				case "esc", "ctrl+c":
+					// Cancel the search.
+					searching = false
+					c.renderer.Prompt("")
+				case "backspace":
+					if len(query) > 0 {
+						_, size := utf8.DecodeLastRuneInString(query)
+						query = query[:len(query)-size]
+					}
+
+					c.renderer.Prompt("/" + query)
+				default:
+					if key.Rune != 0 {
+						query += string(key.Rune)
+						c.renderer.Prompt("/" + query)
+					}
+				}
+
+				continue
+			}
+
+			cmd, ok := c.tailCommand(key)
+
+			if !ok || (panes && !paneCommand(cmd)) {
+				continue
+			}
+
+			switch cmd {
+			case cmdChangeVariant:
+				return true
+			case cmdQuit:
+				return false
+			case cmdPause:
+				if !paused {
+					c.renderer.Paused()
+				}
+
+				paused = true
+			case cmdResume:
+				paused = false
+			case cmdPageUp, cmdPageDown, cmdFirst, cmdLast:
+				pages := 1
+
+				switch cmd {
+				case cmdPageUp:
+					pages = -1
+				case cmdFirst:
+					pages = -allPages
+				case cmdLast:
+					pages = allPages
+				}
+
+				c.renderer.Scroll(pages)
+
+				continue
+			case cmdSearch:
+				searching = true
+				query = ""
+				c.renderer.Prompt("/")
+
+				continue
+			case cmdDiff:
+				diff = !diff
+				c.renderer.Diff(diff)
+
+				// The update loop has no part in how updates are shown.
+				continue
+			}
+
+			queue = append(queue, cmd)
+		}
+	}
+}
+
+// tailCommands are the commands of the actions that can be used while
+// tailing, in the order they are looked up.
+var tailCommands = []struct {
+	action  string
+	command command
+}{
+	{actionQuit, cmdQuit},
+	{actionPause, cmdPause},
+	{actionResume, cmdResume},
+	{actionNext, cmdStep},
+	{actionDiff, cmdDiff},
+	{actionChangeVariant, cmdChangeVariant},
+	{actionPageUp, cmdPageUp},
+	{actionPageDown, cmdPageDown},
+	{actionFirst, cmdFirst},
+	{actionLast, cmdLast},
+	{actionSearch, cmdSearch},
+}
+
+// tailCommand maps a key pressed while tailing to its command.
+func (c *controller) tailCommand(key term.Key) (command, bool) {
+	for _, tc := range tailCommands {
+		if c.keymap.Is(key, tc.action) {
+			return tc.command, true
+		}
+	}
+
+	return 0, false
+}
+
+// paneCommand reports whether cmd can be used while tailing several variants
+// side by side, which have no history to scroll or search and no diff.
+func paneCommand(cmd command) bool {
+	switch cmd {
+	case cmdPageUp, cmdPageDown, cmdFirst, cmdLast, cmdSearch, cmdDiff:
+		return false
+	}
+
+	return true
+}
+
+// updateLoop reloads the variants when the clock says so and sends each set of
+// updates.
+// While paused no requests are made unless the user steps to the next reload.
+// Without user input the loop returns once the clock runs out.
+// With a Selector the master is refreshed every masterInterval, and before
+// the reload after one that failed, to pick the variant again from the latest
+// ladder. The problem with each refresh, or none, is sent to notices.
+func (c *controller) updateLoop(ctx context.Context, commands <-chan command, updates chan<- []*hls.Update, notices chan<- string) {
+	paused := c.paused
+	ended := false
+
+	// The master was fetched just before tailing started.
+	refreshed := time.Now()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	// reload sends the next update and schedules the one after, it returns
+	// false when the loop should stop.
+	reload := func() bool {
+		if c.hls.Selector != nil && time.Since(refreshed) >= c.masterInterval {
+			refreshed = time.Now()
+			notice := ""
+
+			if err := c.hls.RefreshMaster(); err != nil {
+				notice = err.Error()
+			}
+
+			select {
+			case notices <- notice:
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		us := c.hls.ReloadAll(ctx)
+
+		if ctx.Err() != nil {
+			return false
+		}
+
+		for _, u := range us {
+			for _, fn := range c.observers {
+				fn(u)
+			}
+
+			// The variant may have moved or gone from the ladder, pick
+			// again from the latest master before the next reload.
+			if u.Err != nil {
+				refreshed = time.Time{}
+			}
+		}
+
+		select {
+		case updates <- us:
+		case <-ctx.Done():
+			return false
+		}
+
+		// The variants are reloaded together.
+		d, ok := c.clock.next(us)
+
+		if !ok {
+			ended = true
+			return c.keys != nil
+		}
+
+		if !paused {
+			timer.Reset(d)
+		}
+
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case cmd := <-commands:
+			switch cmd {
+			case cmdPause:
+				paused = true
+				stopTimer(timer)
+			case cmdResume:
+				if !paused || ended {
+					paused = false
+					continue
+				}
+
+				paused = false
+
+				// Refresh straight away rather than waiting out the interval.
+				timer.Reset(0)
+			case cmdStep:
+				if ended {
+					continue
+				}
+
+				stopTimer(timer)
+
+				if !reload() {
+					return
+				}
+			}
+		case <-timer.C:
+			if !reload() {
+				return
+			}
+		}
+	}
+}
+
+// notify shows a problem to the user, or clears it when text is empty. Only a
+// change is drawn.
+func (c *controller) notify(text string) {
+	if text == c.notice {
+		return
+	}
+
+	c.notice = text
+	c.renderer.Notice(text)
+}
+
+// notifyErr shows err to the user, or clears the last problem when it is nil.
+func (c *controller) notifyErr(err error) {
+	if err != nil {
+		c.notify(err.Error())
+		return
+	}
+
+	c.notify("")
+}
+
+// stopTimer stops the timer and drains it if it already fired.
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+}
+
+// pollForVariant prompts the user to select a variant, or to mark several to
+// tail side by side. The variants can be sorted, filtered by typing after the
+// search key, or jumped to by typing their number. It returns false if the
+// user quit instead.
+func (c *controller) pollForVariant(ctx context.Context) ([]int, bool) {
+	var marked []int
+
+	// Get the Master and show the variant list to the user.
+	c.renderer.Loading()
+	refreshErr := c.hls.RefreshMaster()
+
+	// The order and filter are kept for when the user changes variant.
+	if c.picker == nil {
+		c.picker = render.NewPicker(c.hls.Master.Variants)
+	} else {
+		c.picker.SetVariants(c.hls.Master.Variants)
+	}
+
+	picker := c.picker
+	selectedIndex := picker.Move(-1, 0)
+	c.renderer.Variants(picker, selectedIndex, marked)
+	c.notifyErr(refreshErr)
+
+	filtering := false
+	number := 0
+
+	// Loop until we have a valid option for a variant to tail.
+	for {
+		var key term.Key
+		var ok bool
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case key, ok = <-c.keys:
+			if !ok {
+				return nil, false
+			}
+		}
+
+		if filtering {
+			filter := picker.Filter()
+
+			switch key.Name {
+			case "enter":
+				filtering = false
+			case "esc", "ctrl+c":
+				filtering = false
+				filter = ""
+			case "backspace":
+				if len(filter) > 0 {
+					_, size := utf8.DecodeLastRuneInString(filter)
+					filter = filter[:len(filter)-size]
+				}
+			default:
+				if key.Rune != 0 {
+					filter += string(key.Rune)
+				}
+			}
+
+			picker.SetFilter(filter)
+
+			if !picker.Visible(selectedIndex) {
+				selectedIndex = picker.Move(selectedIndex, 0)
+			}
+
+			c.renderer.Variants(picker, selectedIndex, marked)
+
+			if filtering {
+				c.renderer.Prompt("/" + filter)
+			} else {
+				c.renderer.Prompt("")
+			}
+
+			continue
+		}
+
+		digit := key.Rune >= '0' && key.Rune <= '9'
+
+		if !digit {
+			number = 0
+		}
+
+		switch {
+		case key.Name == term.KeyResize:
+			// Reprint the list at the new size.
+		case c.keymap.Is(key, actionQuit):
+			return nil, false
+		case c.keymap.Is(key, actionRefresh):
+			// The indexes may no longer match the same variants.
+			marked = nil
+			refreshErr := c.hls.RefreshMaster()
+			picker.SetVariants(c.hls.Master.Variants)
+			selectedIndex = picker.Move(-1, 0)
+			c.notifyErr(refreshErr)
+		case c.keymap.Is(key, actionMark):
+			if picker.Visible(selectedIndex) {
+				marked = toggleMark(marked, selectedIndex)
+			}
+		case c.keymap.Is(key, actionSelect):
+			if len(marked) == 0 {
+				if !picker.Visible(selectedIndex) {
+					continue
+				}
+
+				return []int{selectedIndex}, true
+			}
+
+			sort.Ints(marked)
+
+			return marked, true
+		case c.keymap.Is(key, actionUp):
+			selectedIndex = picker.Move(selectedIndex, -1)
+		case c.keymap.Is(key, actionDown):
+			selectedIndex = picker.Move(selectedIndex, 1)
+		case c.keymap.Is(key, actionFirst):
+			selectedIndex = picker.Move(selectedIndex, -picker.Len())
+		case c.keymap.Is(key, actionLast):
+			selectedIndex = picker.Move(selectedIndex, picker.Len())
+		case c.keymap.Is(key, actionSort):
+			picker.Sort()
+		case c.keymap.Is(key, actionReverse):
+			picker.Reverse()
+		case c.keymap.Is(key, actionSearch):
+			filtering = true
+			c.renderer.Prompt("/" + picker.Filter())
+			continue
+		case digit:
+			// Digits build up the number of a variant, starting again when
+			// there is no variant with that many.
+			number = number*10 + int(key.Rune-'0')
+
+			if number == 0 || number > picker.Len() {
+				number = int(key.Rune - '0')
+			}
+
+			if number == 0 || !picker.Visible(number-1) {
+				continue
+			}
+
+			selectedIndex = number - 1
+		default:
+			// Ignore keys that are not bound.
+			continue
+		}
+
+		// Reprint the variant list.
+		c.renderer.Variants(picker, selectedIndex, marked)
+	}
+}
+
+// toggleMark adds index to marked, or removes it if it is already there.
+func toggleMark(marked []int, index int) []int {
+	for i, m := range marked {
+		if m == index {
+			return append(marked[:i], marked[i+1:]...)
+		}
+	}
+
+	return append(marked, index)
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/moore0n/hlstail/pkg/render"
+	"github.com/moore0n/hlstail/pkg/term"
+)
+
+// testTimeout is how long a test waits for something the controller should
+// do straight away.
+const testTimeout = 2 * time.Second
+
+const testMasterURL = "http://test/master.m3u8"
+
+const testMaster = `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360
+low.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2000000,RESOLUTION=1280x720
+high.m3u8
+`
+
+// fakeFetcher serves the master playlist and a live media playlist for every
+// other url, whose media sequence moves on by one with each request.
+type fakeFetcher struct {
+	mu      sync.Mutex
+	master  string
+	reloads map[string]int
+
+	// block holds media playlist requests until it is closed, or the request
+	// is cancelled.
+	block chan struct{}
+}
+
+func newFakeFetcher() *fakeFetcher {
+	return &fakeFetcher{
+		master:  testMaster,
+		reloads: make(map[string]int),
+	}
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, url string) (*hls.Response, error) {
+	f.mu.Lock()
+	master, block := f.master, f.block
+	f.mu.Unlock()
+
+	if url == testMasterURL {
+		return &hls.Response{URL: url, StatusCode: 200, Body: []byte(master), Fetched: time.Now()}, nil
+	}
+
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	f.mu.Lock()
+	f.reloads[url]++
+	n := f.reloads[url]
+	f.mu.Unlock()
+
+	body := fmt.Sprintf("#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:%d\n#EXTINF:2.000,\n%d.ts\n", n, n)
+
+	return &hls.Response{URL: url, StatusCode: 200, Body: []byte(body), Fetched: time.Now()}, nil
+}
+
+func (f *fakeFetcher) setMaster(master string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.master = master
+}
+
+func (f *fakeFetcher) setBlock(block chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.block = block
+}
+
+func (f *fakeFetcher) reloadCount(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.reloads["http://test/"+name]
+}
+
+// fakeClock allows max reloads, or any number when max is 0, each after d.
+type fakeClock struct {
+	d       time.Duration
+	max     int
+	reloads int
+}
+
+func (c *fakeClock) next(updates []*hls.Update) (time.Duration, bool) {
+	c.reloads++
+
+	if c.max > 0 && c.reloads >= c.max {
+		return 0, false
+	}
+
+	return c.d, true
+}
+
+// recorder is a Renderer that records each call as a line of text.
+type recorder struct {
+	events chan string
+}
+
+func newRecorder() *recorder {
+	return &recorder{events: make(chan string, 1000)}
+}
+
+func (r *recorder) record(format string, args ...interface{}) {
+	r.events <- fmt.Sprintf(format, args...)
+}
+
+func (r *recorder) Start()   { r.record("start") }
+func (r *recorder) Loading() { r.record("loading") }
+func (r *recorder) Paused()  { r.record("paused") }
+func (r *recorder) Resize()  { r.record("resize") }
+func (r *recorder) End()     { r.record("end") }
+
+func (r *recorder) Variants(picker *render.Picker, selectedIndex int, marked []int) {
+	r.record("variants %d", selectedIndex)
+}
+
+func (r *recorder) Update(u *hls.Update) {
+	if u.Err != nil {
+		r.record("update %s error", path.Base(u.URL))
+		return
+	}
+
+	r.record("update %s %d", path.Base(u.URL), u.Playlist.LastSegment().Sequence)
+}
+
+func (r *recorder) Panes(updates []*hls.Update) { r.record("panes %d", len(updates)) }
+func (r *recorder) Diff(show bool)              { r.record("diff %t", show) }
+func (r *recorder) Scroll(pages int)            { r.record("scroll %d", pages) }
+func (r *recorder) Search(query string)         { r.record("search %s", query) }
+func (r *recorder) Prompt(text string)          { r.record("prompt %s", text) }
+func (r *recorder) Notice(text string)          { r.record("notice %s", text) }
+
+// expect waits for the event want, skipping any others before it.
+func (r *recorder) expect(t *testing.T, want string) {
+	t.Helper()
+
+	timeout := time.After(testTimeout)
+
+	for {
+		select {
+		case event := <-r.events:
+			if event == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no %q event", want)
+		}
+	}
+}
+
+// drain returns the events recorded so far.
+func (r *recorder) drain() []string {
+	var events []string
+
+	for {
+		select {
+		case event := <-r.events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// testController is a controller with its fakes, running in the background.
+type testController struct {
+	*controller
+	fetcher  *fakeFetcher
+	renderer *recorder
+	keys     chan term.Key
+	done     chan error
+	cancel   context.CancelFunc
+}
+
+// newTestController creates a controller with keys, or without user input
+// when keys is false.
+func newTestController(t *testing.T, clk clock, keys bool) *testController {
+	t.Helper()
+
+	fetcher := newFakeFetcher()
+	sess, err := hls.NewSession(testMasterURL, fetcher)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := &testController{
+		fetcher:  fetcher,
+		renderer: newRecorder(),
+		done:     make(chan error, 1),
+	}
+
+	var keyChan <-chan term.Key
+
+	if keys {
+		tc.keys = make(chan term.Key)
+		keyChan = tc.keys
+	}
+
+	tc.controller = newController(sess, tc.renderer, keyChan, term.NewKeymap(defaultBindings), clk)
+
+	return tc
+}
+
+// start runs the controller in the background on variants.
+func (tc *testController) start(variants []int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tc.cancel = cancel
+
+	go func() {
+		tc.done <- tc.run(ctx, variants)
+	}()
+}
+
+// press sends the keys named, a single character is typed.
+func (tc *testController) press(t *testing.T, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		key := term.Key{Name: name}
+
+		if len(name) == 1 {
+			key.Rune = rune(name[0])
+		}
+
+		select {
+		case tc.keys <- key:
+		case <-time.After(testTimeout):
+			t.Fatalf("key %s was not read", name)
+		}
+	}
+}
+
+// wait waits for run to return.
+func (tc *testController) wait(t *testing.T) {
+	t.Helper()
+
+	defer tc.cancel()
+
+	select {
+	case err := <-tc.done:
+		if err != nil {
+			t.Fatalf("run failed: %s", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("run did not return")
+	}
+}
+
+func TestControllerStopsWhenClockEnds(t *testing.T) {
+	tc := newTestController(t, &fakeClock{max: 3}, false)
+	tc.start(nil)
+	tc.wait(t)
+
+	want := []string{"update low.m3u8 1", "update low.m3u8 2", "update low.m3u8 3"}
+
+	if got := tc.renderer.drain(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("events %q, want %q", got, want)
+	}
+}
+
+func TestControllerPauseStepResume(t *testing.T) {
+	tc := newTestController(t, &fakeClock{d: time.Hour}, true)
+	tc.startPaused()
+	tc.start([]int{0})
+
+	// The first reload is shown and paused.
+	tc.renderer.expect(t, "update low.m3u8 1")
+	tc.renderer.expect(t, "paused")
+
+	// Next reloads once while staying paused.
+	tc.press(t, "n")
+	tc.renderer.expect(t, "update low.m3u8 2")
+	tc.renderer.expect(t, "paused")
+
+	// Pausing again changes nothing, resuming reloads straight away.
+	tc.press(t, "p", "r")
+	tc.renderer.expect(t, "update low.m3u8 3")
+
+	tc.press(t, "q")
+	tc.wait(t)
+
+	// Nothing else was reloaded, the interval had not passed.
+	if n := tc.fetcher.reloadCount("low.m3u8"); n != 3 {
+		t.Errorf("reloaded %d times, want 3", n)
+	}
+
+	for _, event := range tc.renderer.drain() {
+		if event == "paused" {
+			t.Errorf("paused after resuming")
+		}
+	}
+}
+
+func TestControllerChangeVariant(t *testing.T) {
+	tc := newTestController(t, &fakeClock{d: time.Hour}, true)
+	tc.start([]int{0})
+
+	tc.renderer.expect(t, "update low.m3u8 1")
+
+	tc.press(t, "c")
+	tc.renderer.expect(t, "loading")
+	tc.renderer.expect(t, "variants 0")
+
+	tc.press(t, "j")
+	tc.renderer.expect(t, "variants 1")
+
+	tc.press(t, "enter")
+	tc.renderer.expect(t, "update high.m3u8 1")
+
+	tc.press(t, "q")
+	tc.wait(t)
+}
+
+func TestControllerQuitFromVariants(t *testing.T) {
+	tc := newTestController(t, &fakeClock{d: time.Hour}, true)
+	tc.start(nil)
+
+	tc.renderer.expect(t, "variants 0")
+
+	tc.press(t, "q")
+	tc.wait(t)
+}
+
+func TestControllerCancel(t *testing.T) {
+	tc := newTestController(t, &fakeClock{d: time.Hour}, true)
+	tc.start([]int{0})
+
+	tc.renderer.expect(t, "update low.m3u8 1")
+
+	tc.cancel()
+	tc.wait(t)
+}
+
+func TestControllerCancelDuringReload(t *testing.T) {
+	tc := newTestController(t, &fakeClock{d: time.Hour}, true)
+	tc.fetcher.setBlock(make(chan struct{}))
+	tc.start([]int{0})
+
+	// The first reload never finishes.
+	time.Sleep(10 * time.Millisecond)
+
+	tc.cancel()
+	tc.wait(t)
+}
+
+func TestControllerQueuesCommandsDuringReload(t *testing.T) {
+	tc := newTestController(t, &fakeClock{d: time.Hour}, true)
+	tc.start([]int{0})
+
+	tc.renderer.expect(t, "update low.m3u8 1")
+
+	// The update loop is stuck in the next reload, keys are still read and
+	// their commands queued behind it.
+	block := make(chan struct{})
+	tc.fetcher.setBlock(block)
+
+	tc.press(t, "n", "p", "n")
+	tc.renderer.expect(t, "paused")
+
+	// The queue drains once the reload finishes, the second step reloads
+	// while paused.
+	close(block)
+	tc.renderer.expect(t, "update low.m3u8 2")
+	tc.renderer.expect(t, "update low.m3u8 3")
+	tc.renderer.expect(t, "paused")
+
+	// Quitting does not wait for a reload in flight.
+	tc.fetcher.setBlock(make(chan struct{}))
+	tc.press(t, "n", "q")
+	tc.wait(t)
+}
+
+func TestControllerPanesIgnoreHistoryKeys(t *testing.T) {
+	tc := newTestController(t, &fakeClock{d: time.Hour}, true)
+	tc.start([]int{0, 1})
+
+	tc.renderer.expect(t, "panes 2")
+
+	tc.press(t, "d", "pgup", "G", "/", "x", "q")
+	tc.wait(t)
+
+	for _, event := range tc.renderer.drain() {
+		if !strings.HasPrefix(event, "panes") {
+			t.Errorf("unexpected %q in panes", event)
+		}
+	}
+}
+
+func TestControllerSelectorFollowsMaster(t *testing.T) {
+	tc := newTestController(t, &fakeClock{d: 5 * time.Millisecond}, true)
+
+	// Refresh the master before every reload.
+	tc.masterInterval = 0
+
+	if err := tc.hls.Select(&hls.Selector{Resolution: "1280x720"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tc.start(nil)
+	tc.renderer.expect(t, "update high.m3u8 1")
+
+	// The variant keeps working when it leaves the ladder, the problem is
+	// shown until it is back.
+	tc.fetcher.setMaster("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360\nlow.m3u8\n")
+	tc.renderer.expect(t, "notice no variant matches resolution 1280x720, still tailing 1280x720 2000000")
+
+	tc.fetcher.setMaster("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=2500000,RESOLUTION=1280x720\nhd.m3u8\n")
+	tc.renderer.expect(t, "notice ")
+	tc.renderer.expect(t, "update hd.m3u8 1")
+
+	tc.press(t, "q")
+	tc.wait(t)
+}
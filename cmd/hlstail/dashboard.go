@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/dashboard"
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/moore0n/hlstail/pkg/render"
+	"github.com/moore0n/hlstail/pkg/term"
+	"github.com/urfave/cli/v2"
+)
+
+// dashboardCommand monitors the streams listed in a config file.
+func dashboardCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "dashboard",
+		Usage:     "Monitor many streams at once with a row per stream, select one to tail it",
+		ArgsUsage: "<streams.yaml>",
+		Description: "The config lists the streams to monitor and how often to reload them:\n\n" +
+			"   interval: 5s\n" +
+			"   streams:\n" +
+			"     - name: News\n" +
+			"       url: https://example.com/news/master.m3u8\n" +
+			"       variant: 2\n" +
+			"     - https://example.com/sport/master.m3u8",
+		Flags: append([]cli.Flag{
+			&cli.IntFlag{
+				Name:  "interval",
+				Usage: "The number of seconds to wait between reloads, 0 uses the interval of the config",
+			},
+			&cli.IntFlag{
+				Name:  "count",
+				Usage: "The number of segments to display when tailing a stream, 0 fits as many as the terminal has room for",
+			},
+			keymapFlag(),
+		}, harFlags()...),
+		Action: func(c *cli.Context) error {
+			file := c.Args().Get(0)
+
+			if file == "" {
+				cli.ShowCommandHelpAndExit(c, "dashboard", 0)
+			}
+
+			config, err := dashboard.LoadConfig(file)
+
+			if err != nil {
+				return err
+			}
+
+			if interval := c.Int("interval"); interval > 0 {
+				config.Interval = time.Duration(interval) * time.Second
+			}
+
+			keymap, err := newKeymap(c.String("keymap"))
+
+			if err != nil {
+				return err
+			}
+
+			client, saveHAR := newClient(c)
+			fetcher := hls.NewFetcher(client)
+
+			err = runDashboard(config, fetcher, keymap, c.Int("count"))
+
+			if harErr := saveHAR(); err == nil {
+				err = harErr
+			}
+
+			return err
+		},
+	}
+}
+
+// runDashboard monitors the streams until the user quits or hlstail is asked
+// to stop. Without a terminal a line is written for every reload instead.
+func runDashboard(config *dashboard.Config, fetcher hls.Fetcher, keymap *term.Keymap, count int) error {
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	monitor := dashboard.NewMonitor(config, fetcher)
+
+	if !term.IsTerminal(os.Stdout) || !term.IsTerminal(os.Stdin) {
+		text := render.NewDashboardText(os.Stdout)
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			monitor.Run(ctx)
+		}()
+
+		// Changes are coalesced, print every stream that reloaded since the last.
+		seen := make(map[int]int)
+
+		for {
+			select {
+			case <-done:
+				return nil
+			case <-monitor.Changed():
+				for i, st := range monitor.Statuses() {
+					if st.Reloads != seen[i] {
+						seen[i] = st.Reloads
+						text.Status(st)
+					}
+				}
+			}
+		}
+	}
+
+	termSess := term.NewSession()
+
+	if err := termSess.MakeRaw(); err != nil {
+		return err
+	}
+
+	keys := termSess.ReadKeys(ctx)
+
+	go monitor.Run(ctx)
+
+	screen := render.NewDashboardScreen(termSess)
+	screen.Start()
+	defer screen.End()
+
+	dashboardLoop(ctx, screen, monitor, keys, keymap, func(stream dashboard.Stream) {
+		tailStream(ctx, termSess, keys, keymap, fetcher, stream, config.Interval, count)
+	})
+
+	return nil
+}
+
+// dashboardLoop redraws the streams and moves the selection until the user
+// quits, tail is called with the stream selected with enter.
+func dashboardLoop(ctx context.Context, screen *render.DashboardScreen, monitor *dashboard.Monitor, keys <-chan term.Key, keymap *term.Keymap, tail func(dashboard.Stream)) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	selectedIndex := 0
+
+	for {
+		statuses := monitor.Statuses()
+		screen.Draw(statuses, selectedIndex)
+
+		select {
+		case <-ctx.Done():
+			return
+		case key, ok := <-keys:
+			if !ok {
+				return
+			}
+
+			switch {
+			case keymap.Is(key, actionQuit):
+				return
+			case keymap.Is(key, actionSelect):
+				tail(statuses[selectedIndex].Stream)
+			case keymap.Is(key, actionUp):
+				if selectedIndex > 0 {
+					selectedIndex--
+				}
+			case keymap.Is(key, actionDown):
+				if selectedIndex < len(statuses)-1 {
+					selectedIndex++
+				}
+			case keymap.Is(key, actionFirst):
+				selectedIndex = 0
+			case keymap.Is(key, actionLast):
+				selectedIndex = len(statuses) - 1
+			}
+		case <-monitor.Changed():
+		case <-ticker.C:
+		}
+	}
+}
+
+// tailStream shows the tail view of a stream until the user quits it. The
+// monitor carries on in the background.
+func tailStream(ctx context.Context, termSess *term.Session, keys <-chan term.Key, keymap *term.Keymap, fetcher hls.Fetcher, stream dashboard.Stream, interval time.Duration, count int) {
+	renderer := render.NewScreen(termSess, count, defaultHistory)
+	renderer.Loading()
+
+	sess, err := hls.NewSession(stream.URL, fetcher)
+
+	// The dashboard row already shows why the stream could not be loaded.
+	if err != nil {
+		return
+	}
+
+	var variants []int
+
+	if stream.Variant <= len(sess.Master.Variants) {
+		variants = []int{stream.Variant - 1}
+	}
+
+	newController(sess, renderer, keys, keymap, intervalClock(interval)).run(ctx, variants)
+}
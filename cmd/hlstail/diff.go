@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/moore0n/hlstail/pkg/term"
+	"github.com/moore0n/hlstail/pkg/tools"
+	"github.com/urfave/cli/v2"
+)
+
+// diffCommand prints the differences between two playlists.
+func diffCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "Show a unified diff of two playlists, each a URL, a file or - for stdin",
+		ArgsUsage: "<playlist> <playlist>",
+		Flags: append([]cli.Flag{
+			&cli.IntFlag{
+				Name:  "context",
+				Usage: "The number of unchanged lines to show around each change",
+				Value: 3,
+			},
+		}, harFlags()...),
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 {
+				cli.ShowCommandHelpAndExit(c, "diff", 0)
+			}
+
+			ctx, cancel := signalContext()
+			defer cancel()
+
+			client, saveHAR := newClient(c)
+			fetcher := hls.NewFetcher(client)
+
+			var b string
+
+			a, err := loadPlaylist(ctx, fetcher, c.Args().Get(0))
+
+			if err == nil {
+				b, err = loadPlaylist(ctx, fetcher, c.Args().Get(1))
+			}
+
+			if harErr := saveHAR(); err == nil {
+				err = harErr
+			}
+
+			if err != nil {
+				return err
+			}
+
+			diff := tools.UnifiedDiff(c.Args().Get(0), c.Args().Get(1), tools.SplitLines(a), tools.SplitLines(b), c.Int("context"))
+
+			if term.IsTerminal(os.Stdout) {
+				diff = colorDiff(diff)
+			}
+
+			fmt.Print(diff)
+
+			return nil
+		},
+	}
+}
+
+// loadPlaylist reads a playlist from a url, a file or standard input.
+func loadPlaylist(ctx context.Context, fetcher hls.Fetcher, location string) (string, error) {
+	resp, err := fetcher.Fetch(ctx, hls.Location(location))
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp.Body), nil
+}
+
+// colorDiff colors a unified diff the same way as the diff view.
+func colorDiff(diff string) string {
+	lines := strings.Split(diff, "\n")
+
+	for i, line := range lines {
+		color := ""
+
+		switch {
+		case strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++"):
+			color = "\033[1m"
+		case strings.HasPrefix(line, "@@"):
+			color = "\033[38;5;44m"
+		case (strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")) && hls.IsPlaylistTag(line[1:]):
+			color = "\033[38;5;226m"
+		case strings.HasPrefix(line, "+"):
+			color = "\033[38;5;40m"
+		case strings.HasPrefix(line, "-"):
+			color = "\033[38;5;160m"
+		}
+
+		if color != "" {
+			lines[i] = fmt.Sprintf("%s%s\033[0m", color, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
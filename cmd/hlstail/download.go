@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/moore0n/hlstail/pkg/capture"
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/urfave/cli/v2"
+)
+
+// downloadCommand downloads every segment of a VOD variant.
+func downloadCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "download",
+		Usage:     "Download every segment of a VOD variant, resuming from a partial download",
+		ArgsUsage: "<playlist>",
+		Flags: append(append(variantFlags(),
+			&cli.StringFlag{
+				Name:  "out",
+				Usage: "The directory to download to",
+				Value: "download",
+			},
+			&cli.IntFlag{
+				Name:  "workers",
+				Usage: "The number of segments to download at once",
+				Value: 4,
+			},
+			&cli.BoolFlag{
+				Name:  "concat",
+				Usage: "Join the segments into a single file, e.g. for MPEG-TS",
+			},
+		), harFlags()...),
+		Action: func(c *cli.Context) error {
+			playlist := c.Args().Get(0)
+
+			if playlist == "" {
+				cli.ShowCommandHelpAndExit(c, "download", 0)
+			}
+
+			ctx, cancel := signalContext()
+			defer cancel()
+
+			client, saveHAR := newClient(c)
+
+			master := hls.NewMaster(playlist)
+			master.Fetcher = hls.NewFetcher(client)
+
+			err := master.Get()
+
+			if err == nil {
+				err = download(ctx, master, client, c)
+			}
+
+			if harErr := saveHAR(); err == nil {
+				err = harErr
+			}
+
+			return err
+		},
+	}
+}
+
+// download fetches the selected variant of master.
+func download(ctx context.Context, master *hls.Master, client *http.Client, c *cli.Context) error {
+	index, err := selectVariant(c, master)
+
+	if err != nil {
+		return err
+	}
+
+	downloader, err := capture.NewDownloader(master, index, capture.DownloadOptions{
+		Dir:     c.String("out"),
+		Workers: c.Int("workers"),
+		Concat:  c.Bool("concat"),
+		Client:  client,
+		Log:     os.Stdout,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return downloader.Run(ctx)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/moore0n/hlstail/pkg/har"
+	"github.com/urfave/cli/v2"
+)
+
+// harFlags returns the flags that record requests to an HTTP Archive.
+func harFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "har",
+			Usage: "Write every request made to `FILE` in HTTP Archive format",
+		},
+		&cli.BoolFlag{
+			Name:  "har-bodies",
+			Usage: "Include the playlists in the HTTP Archive",
+		},
+	}
+}
+
+// newClient returns the client requests are made with. With --har it records
+// them, and save writes the archive.
+func newClient(c *cli.Context) (client *http.Client, save func() error) {
+	file := c.String("har")
+
+	if file == "" {
+		return http.DefaultClient, func() error { return nil }
+	}
+
+	transport := har.NewTransport(nil, har.Options{
+		Creator: har.Creator{Name: c.App.Name, Version: c.App.Version},
+		Bodies:  c.Bool("har-bodies"),
+	})
+
+	save = func() error {
+		if err := transport.WriteFile(file); err != nil {
+			return fmt.Errorf("writing %s: %w", file, err)
+		}
+
+		return nil
+	}
+
+	return &http.Client{Transport: transport}, save
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/moore0n/hlstail/pkg/term"
+	"github.com/urfave/cli/v2"
+)
+
+// Actions that keys can be bound to in a keymap file.
+const (
+	actionQuit          = "quit"
+	actionUp            = "up"
+	actionDown          = "down"
+	actionFirst         = "first"
+	actionLast          = "last"
+	actionSelect        = "select"
+	actionMark          = "mark"
+	actionRefresh       = "refresh"
+	actionPause         = "pause"
+	actionResume        = "resume"
+	actionNext          = "next"
+	actionDiff          = "diff"
+	actionChangeVariant = "change-variant"
+	actionPageUp        = "page-up"
+	actionPageDown      = "page-down"
+	actionSearch        = "search"
+	actionSort          = "sort"
+	actionReverse       = "reverse"
+)
+
+// defaultBindings are the keys of each action before the keymap file is
+// read. Up, down, first and last move through lists and the history.
+var defaultBindings = map[string][]string{
+	actionQuit:          {"q", "ctrl+c"},
+	actionUp:            {"up", "k"},
+	actionDown:          {"down", "j"},
+	actionFirst:         {"home", "g"},
+	actionLast:          {"end", "G"},
+	actionSelect:        {"enter"},
+	actionMark:          {"space"},
+	actionRefresh:       {"r"},
+	actionPause:         {"p"},
+	actionResume:        {"r"},
+	actionNext:          {"n"},
+	actionDiff:          {"d"},
+	actionChangeVariant: {"c"},
+	actionPageUp:        {"pgup", "ctrl+b", "wheelup"},
+	actionPageDown:      {"pgdn", "ctrl+f", "wheeldown"},
+	actionSearch:        {"/"},
+	actionSort:          {"s"},
+	actionReverse:       {"S"},
+}
+
+// keymapFile is where the keymap is read from when --keymap is not given,
+// it is fine for it not to exist.
+func keymapFile() string {
+	dir, err := os.UserConfigDir()
+
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "hlstail", "keys")
+}
+
+// keymapFlag is the flag of the commands that read a keymap.
+func keymapFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "keymap",
+		Usage: "The file of key bindings, each line an action and its keys e.g. down = j down, by default hlstail/keys in the user config directory",
+	}
+}
+
+// newKeymap reads the keymap from file, or from the default location when
+// file is empty where it does not have to exist.
+func newKeymap(file string) (*term.Keymap, error) {
+	keymap := term.NewKeymap(defaultBindings)
+
+	if file == "" {
+		file = keymapFile()
+
+		if _, err := os.Stat(file); file == "" || err != nil {
+			return keymap, nil
+		}
+	}
+
+	if err := keymap.Load(file); err != nil {
+		return nil, err
+	}
+
+	return keymap, nil
+}
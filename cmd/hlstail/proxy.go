@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/proxy"
+	"github.com/moore0n/hlstail/pkg/render"
+	"github.com/moore0n/hlstail/pkg/term"
+	"github.com/urfave/cli/v2"
+)
+
+// proxyCommand runs a proxy between a player and the origin that injects
+// faults, or shows what the player requests with --observe.
+func proxyCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "proxy",
+		Usage:     "Proxy a stream for a player, injecting faults or observing what it requests",
+		ArgsUsage: "<playlist>",
+		Description: "Faults are given as kind:name[=value][@probability] where kind is all, master, media, segment or key.\n" +
+			"   latency=2s adds a delay, status=503 replaces the response, truncate=0.5 cuts the body short,\n" +
+			"   stale serves the previous version of a playlist, drop removes a segment from a media playlist\n" +
+			"   and jump=5 moves its media sequence on. e.g. --fault segment:status=503@0.1 --fault media:stale@0.2",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "addr",
+				Usage: "The address to listen on",
+				Value: "localhost:8081",
+			},
+			&cli.StringSliceFlag{
+				Name:  "fault",
+				Usage: "A fault to inject, may be repeated",
+			},
+			&cli.Int64Flag{
+				Name:  "seed",
+				Usage: "Seed the fault probabilities to repeat a run, 0 picks a seed",
+			},
+			&cli.BoolFlag{
+				Name:  "observe",
+				Usage: "Show the requests the player makes, how far behind live it is and when it switches variant",
+			},
+			&cli.IntFlag{
+				Name:  "count",
+				Usage: "The number of requests to display with --observe",
+				Value: 15,
+			},
+			keymapFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			playlist := c.Args().Get(0)
+
+			if playlist == "" {
+				cli.ShowCommandHelpAndExit(c, "proxy", 0)
+			}
+
+			path, err := proxy.Path(playlist)
+
+			if err != nil {
+				return err
+			}
+
+			var faults []*proxy.Fault
+
+			for _, spec := range c.StringSlice("fault") {
+				f, err := proxy.ParseFault(spec)
+
+				if err != nil {
+					return err
+				}
+
+				faults = append(faults, f)
+			}
+
+			seed := c.Int64("seed")
+
+			if seed == 0 {
+				seed = time.Now().UnixNano()
+			}
+
+			ctx, cancel := signalContext()
+			defer cancel()
+
+			listener, err := net.Listen("tcp", c.String("addr"))
+
+			if err != nil {
+				return err
+			}
+
+			opts := proxy.Options{
+				Faults: faults,
+				Seed:   seed,
+				Log:    os.Stdout,
+			}
+
+			var observer *proxy.Observer
+			var screen *render.ObserveScreen
+			var keys <-chan term.Key
+			var keymap *term.Keymap
+
+			if c.Bool("observe") {
+				observer = proxy.NewObserver()
+				opts.Log = nil
+
+				if term.IsTerminal(os.Stdout) && term.IsTerminal(os.Stdin) {
+					keymap, err = newKeymap(c.String("keymap"))
+
+					if err != nil {
+						return err
+					}
+
+					termSess := term.NewSession()
+
+					if err := termSess.MakeRaw(); err != nil {
+						return err
+					}
+
+					screen = render.NewObserveScreen(termSess, c.Int("count"))
+					keys = termSess.ReadKeys(ctx)
+					opts.Observe = func(r *proxy.Request) { observer.Observe(r) }
+				} else {
+					text := render.NewObserveText(os.Stdout)
+					opts.Observe = func(r *proxy.Request) { text.Observation(observer.Observe(r)) }
+				}
+			}
+
+			url := fmt.Sprintf("http://%s%s", listener.Addr(), path)
+
+			if screen == nil {
+				fmt.Printf("seed %d\n", seed)
+				fmt.Printf("serving %s\n", url)
+			}
+
+			server := &http.Server{Handler: proxy.New(opts)}
+
+			go func() {
+				<-ctx.Done()
+				server.Close()
+			}()
+
+			if screen != nil {
+				go server.Serve(listener)
+
+				screen.Start()
+				defer screen.End()
+
+				observeLoop(ctx, screen, observer, keys, keymap, url)
+
+				return nil
+			}
+
+			if err := server.Serve(listener); err != http.ErrServerClosed {
+				return err
+			}
+
+			return nil
+		},
+	}
+}
+
+// observeLoop redraws the requests seen by observer until the user quits.
+func observeLoop(ctx context.Context, screen *render.ObserveScreen, observer *proxy.Observer, keys <-chan term.Key, keymap *term.Keymap, url string) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		screen.Draw(observer.Report(), url)
+
+		select {
+		case <-ctx.Done():
+			return
+		case key, ok := <-keys:
+			if !ok || keymap.Is(key, actionQuit) {
+				return
+			}
+		case <-observer.Changed():
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/capture"
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/urfave/cli/v2"
+)
+
+// recordCommand records the segments of a live variant to disk.
+func recordCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "record",
+		Usage:     "Download the segments of a live variant and its renditions into a local VOD playlist",
+		ArgsUsage: "<playlist>",
+		Flags: append(append(variantFlags(),
+			&cli.StringFlag{
+				Name:  "out",
+				Usage: "The directory to write the recording to",
+				Value: "recording",
+			},
+			&cli.IntFlag{
+				Name:  "interval",
+				Usage: "The number of seconds to wait between updates, 0 uses half the target duration",
+			},
+		), harFlags()...),
+		Action: func(c *cli.Context) error {
+			playlist := c.Args().Get(0)
+
+			if playlist == "" {
+				cli.ShowCommandHelpAndExit(c, "record", 0)
+			}
+
+			ctx, cancel := signalContext()
+			defer cancel()
+
+			client, saveHAR := newClient(c)
+
+			master := hls.NewMaster(playlist)
+			master.Fetcher = hls.NewFetcher(client)
+
+			err := master.Get()
+
+			if err == nil {
+				err = record(ctx, master, client, c)
+			}
+
+			if harErr := saveHAR(); err == nil {
+				err = harErr
+			}
+
+			return err
+		},
+	}
+}
+
+// record runs the DVR on the selected variant of master.
+func record(ctx context.Context, master *hls.Master, client *http.Client, c *cli.Context) error {
+	index, err := selectVariant(c, master)
+
+	if err != nil {
+		return err
+	}
+
+	dvr, err := capture.NewDVR(master, index, capture.DVROptions{
+		Dir:      c.String("out"),
+		Interval: time.Duration(c.Int("interval")) * time.Second,
+		Client:   client,
+		Log:      os.Stdout,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return dvr.Run(ctx)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/archive"
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/urfave/cli/v2"
+)
+
+// replayClock paces reloads by the time between recorded snapshots. With
+// several panes the next reload is at the next snapshot of any of them.
+type replayClock struct {
+	player *archive.Player
+	speed  float64
+}
+
+func (c *replayClock) next(updates []*hls.Update) (time.Duration, bool) {
+	urls := make([]string, len(updates))
+
+	for i, u := range updates {
+		urls[i] = u.URL
+	}
+
+	d, err := c.player.Next(urls...)
+
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(float64(d) / c.speed), true
+}
+
+// replayCommand replays playlists saved with --record.
+func replayCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "replay",
+		Usage:     "Replay playlists saved with --record through the tail view",
+		ArgsUsage: "<directory>",
+		Flags: append(sessionFlags(),
+			&cli.Float64Flag{
+				Name:  "speed",
+				Usage: "How much faster than real time to replay, e.g. 10",
+				Value: 1,
+			},
+			&cli.BoolFlag{
+				Name:  "step",
+				Usage: "Start paused and step through the reloads with (n)ext",
+			},
+		),
+		Action: func(c *cli.Context) error {
+			dir := c.Args().Get(0)
+
+			if dir == "" {
+				cli.ShowCommandHelpAndExit(c, "replay", 0)
+			}
+
+			speed := c.Float64("speed")
+
+			if speed <= 0 {
+				return errors.New("speed must be greater than 0")
+			}
+
+			player, err := archive.OpenPlayer(dir)
+
+			if err != nil {
+				return err
+			}
+
+			clk := &replayClock{player: player, speed: speed}
+
+			return runSession(player.URL(), player, clk, c.Bool("step"), newSessionOptions(c))
+		},
+	}
+}
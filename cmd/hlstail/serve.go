@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/origin"
+	"github.com/urfave/cli/v2"
+)
+
+// serveCommand serves a local VOD playlist as a looping live stream.
+func serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "serve",
+		Usage:     "Serve a local VOD playlist and its segments as a sliding window live stream",
+		ArgsUsage: "<playlist>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "addr",
+				Usage: "The address to listen on",
+				Value: "localhost:8080",
+			},
+			&cli.IntFlag{
+				Name:  "window",
+				Usage: "The number of segments in each live playlist",
+				Value: 6,
+			},
+			&cli.IntFlag{
+				Name:  "target-duration",
+				Usage: "The EXT-X-TARGETDURATION to advertise, 0 uses the longest segment",
+			},
+			&cli.BoolFlag{
+				Name:  "discontinuity",
+				Usage: "Mark the start of every loop with EXT-X-DISCONTINUITY",
+				Value: true,
+			},
+			&cli.Float64Flag{
+				Name:  "part-duration",
+				Usage: "Split segments into LL-HLS parts of this many seconds, 0 disables parts",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			playlist := c.Args().Get(0)
+
+			if playlist == "" {
+				cli.ShowCommandHelpAndExit(c, "serve", 0)
+			}
+
+			ctx, cancel := signalContext()
+			defer cancel()
+
+			o, err := origin.NewOrigin(playlist, origin.Options{
+				Window:         c.Int("window"),
+				TargetDuration: c.Int("target-duration"),
+				Discontinuity:  c.Bool("discontinuity"),
+				PartDuration:   time.Duration(c.Float64("part-duration") * float64(time.Second)),
+			})
+
+			if err != nil {
+				return err
+			}
+
+			listener, err := net.Listen("tcp", c.String("addr"))
+
+			if err != nil {
+				return err
+			}
+
+			for _, p := range o.Playlists() {
+				fmt.Printf("serving http://%s%s\n", listener.Addr(), p)
+			}
+
+			server := &http.Server{Handler: o}
+
+			go func() {
+				<-ctx.Done()
+				server.Close()
+			}()
+
+			if err := server.Serve(listener); err != http.ErrServerClosed {
+				return err
+			}
+
+			return nil
+		},
+	}
+}
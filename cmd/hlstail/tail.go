@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/archive"
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/moore0n/hlstail/pkg/metrics"
+	"github.com/moore0n/hlstail/pkg/render"
+	"github.com/moore0n/hlstail/pkg/term"
+	"github.com/urfave/cli/v2"
+)
+
+// Output formats.
+const (
+	outputAuto = "auto"
+	outputTUI  = "tui"
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// defaultHistory is the number of segments kept to scroll back through.
+const defaultHistory = 1000
+
+// defaultTextCount is the number of segments the text output starts with
+// when --count is left to fit the screen.
+const defaultTextCount = 5
+
+// sessionOptions are the options shared by every command that tails a variant.
+type sessionOptions struct {
+	count       int
+	history     int
+	variant     variantOptions
+	panes       []int
+	output      string
+	metricsAddr string
+	keymap      string
+	mouse       bool
+}
+
+// sessionFlags returns the flags that fill in sessionOptions.
+func sessionFlags() []cli.Flag {
+	flags := []cli.Flag{
+		&cli.IntFlag{
+			Name:  "count",
+			Usage: "The number of segments to display, 0 fits as many as the terminal has room for",
+		},
+		&cli.IntFlag{
+			Name:  "history",
+			Usage: "The number of segments to keep for scrolling back and searching",
+			Value: defaultHistory,
+		},
+	}
+
+	return append(append(flags, variantFlags()...),
+		&cli.IntSliceFlag{
+			Name:  "panes",
+			Usage: "The numbers of the variants to tail side by side, repeat it for each, e.g. --panes 1 --panes 3",
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "The output format: auto, tui, text or json",
+			Value: outputAuto,
+		},
+		&cli.StringFlag{
+			Name:  "metrics-addr",
+			Usage: "The address to serve Prometheus metrics on, e.g. :9090",
+		},
+		keymapFlag(),
+		&cli.BoolFlag{
+			Name:  "mouse",
+			Usage: "Scroll the segment history with the mouse wheel",
+		},
+	)
+}
+
+// newSessionOptions reads the session flags.
+func newSessionOptions(c *cli.Context) sessionOptions {
+	return sessionOptions{
+		count:       c.Int("count"),
+		history:     c.Int("history"),
+		variant:     newVariantOptions(c),
+		panes:       c.IntSlice("panes"),
+		output:      c.String("output"),
+		metricsAddr: c.String("metrics-addr"),
+		keymap:      c.String("keymap"),
+		mouse:       c.Bool("mouse"),
+	}
+}
+
+// tailFlags returns the flags of the default command.
+func tailFlags() []cli.Flag {
+	flags := append(sessionFlags(),
+		&cli.IntFlag{
+			Name:  "interval",
+			Usage: "The number of seconds to wait between updates",
+			Value: 3,
+		},
+		&cli.StringFlag{
+			Name:  "record",
+			Usage: "The directory to save every fetched playlist in",
+		},
+		&cli.Int64Flag{
+			Name:  "record-max-size",
+			Usage: "The number of megabytes of recorded playlists to keep, 0 keeps everything",
+		},
+		&cli.DurationFlag{
+			Name:  "record-max-age",
+			Usage: "How long to keep recorded playlists, e.g. 24h, 0 keeps everything",
+		},
+	)
+
+	return append(flags, harFlags()...)
+}
+
+// tailAction tails a live playlist.
+func tailAction(c *cli.Context) error {
+	playlist := c.Args().Get(0)
+
+	// Validate that we have a playlist value.
+	if playlist == "" {
+		cli.ShowAppHelpAndExit(c, 0)
+	}
+
+	client, saveHAR := newClient(c)
+
+	fetcher := hls.NewFetcher(client)
+	var recorder *archive.Recorder
+
+	if dir := c.String("record"); dir != "" {
+		r, err := archive.NewRecorder(fetcher, dir, archive.Options{
+			MaxSize: c.Int64("record-max-size") * 1024 * 1024,
+			MaxAge:  c.Duration("record-max-age"),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		recorder = r
+		fetcher = r
+	}
+
+	interval := intervalClock(time.Duration(c.Int("interval")) * time.Second)
+
+	err := runSession(playlist, fetcher, interval, false, newSessionOptions(c))
+
+	// The archive matters most when something went wrong, so write it anyway.
+	if harErr := saveHAR(); err == nil {
+		err = harErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if recorder != nil && recorder.Err() != nil {
+		return fmt.Errorf("recording playlists: %w", recorder.Err())
+	}
+
+	return nil
+}
+
+// runSession tails a variant of playlist until the user quits or hlstail is
+// asked to stop.
+func runSession(playlist string, fetcher hls.Fetcher, clk clock, paused bool, opts sessionOptions) error {
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	keymap, err := newKeymap(opts.keymap)
+
+	if err != nil {
+		return err
+	}
+
+	selector, err := opts.variant.selector()
+
+	if err != nil {
+		return err
+	}
+
+	renderer, keys, err := newRenderer(ctx, opts)
+
+	if err != nil {
+		return err
+	}
+
+	// Start the new terminal session
+	renderer.Start()
+	defer renderer.End()
+
+	// Print the loading screen here before we make the request.
+	renderer.Loading()
+
+	// Create a new HLS Session to manage the requests.
+	sess, err := hls.NewSession(playlist, fetcher)
+
+	if err != nil {
+		return err
+	}
+
+	ctrl := newController(sess, renderer, keys, keymap, clk)
+
+	if paused {
+		ctrl.startPaused()
+	}
+
+	if opts.metricsAddr != "" {
+		collector, err := serveMetrics(ctx, opts.metricsAddr)
+
+		if err != nil {
+			return err
+		}
+
+		ctrl.observe(func(u *hls.Update) {
+			collector.Observe(playlist, u.URL, u)
+		})
+	}
+
+	var variants []int
+
+	// Panes are numbered from 1 as they are in the variant list.
+	for _, pane := range opts.panes {
+		if pane < 1 || pane > len(sess.Master.Variants) {
+			return fmt.Errorf("no variant %d, the playlist has %d", pane, len(sess.Master.Variants))
+		}
+
+		variants = append(variants, pane-1)
+	}
+
+	if selector != nil {
+		if len(variants) > 0 {
+			return errors.New("--panes can't be used with a variant selector")
+		}
+
+		if err := sess.Select(selector); err != nil {
+			return err
+		}
+	}
+
+	return ctrl.run(ctx, variants)
+}
+
+// newRenderer creates the renderer for the output option. Keys are only read
+// when the output is the terminal user interface.
+func newRenderer(ctx context.Context, opts sessionOptions) (render.Renderer, <-chan term.Key, error) {
+	output := opts.output
+
+	// Only take over the screen when a person is watching it, otherwise write
+	// plain lines that can be piped or logged.
+	if output == outputAuto {
+		output = outputText
+
+		if term.IsTerminal(os.Stdout) && term.IsTerminal(os.Stdin) {
+			output = outputTUI
+		}
+	}
+
+	switch output {
+	case outputTUI:
+		termSess := term.NewSession()
+
+		if err := termSess.MakeRaw(); err != nil {
+			return nil, nil, err
+		}
+
+		if opts.mouse {
+			termSess.EnableMouse()
+		}
+
+		return render.NewScreen(termSess, opts.count, opts.history), termSess.ReadKeys(ctx), nil
+	case outputText:
+		count := opts.count
+
+		if count == 0 {
+			count = defaultTextCount
+		}
+
+		return render.NewText(os.Stdout, count), nil, nil
+	case outputJSON:
+		return render.NewJSON(os.Stdout), nil, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown output %q", opts.output)
+}
+
+// signalContext returns a context that is cancelled when hlstail is asked to
+// stop.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		defer signal.Stop(signals)
+
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// serveMetrics serves Prometheus metrics on addr until ctx is cancelled.
+func serveMetrics(ctx context.Context, addr string) (*metrics.Collector, error) {
+	listener, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	registry := metrics.NewRegistry()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+
+	server := &http.Server{Handler: mux}
+
+	go server.Serve(listener)
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	return metrics.NewCollector(registry), nil
+}
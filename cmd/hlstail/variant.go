@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/urfave/cli/v2"
+)
+
+// variantOptions choose a variant of the master by its number or attributes.
+type variantOptions struct {
+	variant      string
+	resolution   string
+	bandwidthMax int
+	codec        string
+	urlMatch     string
+}
+
+// variantFlags returns the flags that fill in variantOptions.
+func variantFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "variant",
+			Usage: "The variant to use: its number as listed, highest or lowest bandwidth",
+		},
+		&cli.StringFlag{
+			Name:  "resolution",
+			Usage: "Use the highest variant with this resolution, e.g. 1280x720",
+		},
+		&cli.IntFlag{
+			Name:  "bandwidth-max",
+			Usage: "Use the highest variant with at most this bandwidth",
+		},
+		&cli.StringFlag{
+			Name:  "codec",
+			Usage: "Use the highest variant with a codec starting with this, e.g. hvc1",
+		},
+		&cli.StringFlag{
+			Name:  "variant-url-match",
+			Usage: "Use the highest variant whose url matches this regular expression",
+		},
+	}
+}
+
+// newVariantOptions reads the variant flags.
+func newVariantOptions(c *cli.Context) variantOptions {
+	return variantOptions{
+		variant:      c.String("variant"),
+		resolution:   c.String("resolution"),
+		bandwidthMax: c.Int("bandwidth-max"),
+		codec:        c.String("codec"),
+		urlMatch:     c.String("variant-url-match"),
+	}
+}
+
+// selector builds the variant selector from the options, nil when no variant
+// was asked for.
+func (opts variantOptions) selector() (*hls.Selector, error) {
+	selector, err := hls.ParseSelector(opts.variant)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.resolution == "" && opts.bandwidthMax == 0 && opts.codec == "" && opts.urlMatch == "" {
+		return selector, nil
+	}
+
+	if selector == nil {
+		selector = &hls.Selector{}
+	}
+
+	selector.Resolution = opts.resolution
+	selector.BandwidthMax = opts.bandwidthMax
+	selector.Codec = opts.codec
+
+	if opts.urlMatch != "" {
+		re, err := regexp.Compile(opts.urlMatch)
+
+		if err != nil {
+			return nil, fmt.Errorf("--variant-url-match: %w", err)
+		}
+
+		selector.URLMatch = re
+	}
+
+	return selector, nil
+}
+
+// selectVariant returns the index of the variant of master the flags ask
+// for, the first when they ask for none.
+func selectVariant(c *cli.Context, master *hls.Master) (int, error) {
+	selector, err := newVariantOptions(c).selector()
+
+	if err != nil || selector == nil {
+		return 0, err
+	}
+
+	return selector.Select(master.Variants)
+}
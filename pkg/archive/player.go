@@ -0,0 +1,173 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// Player is an hls.Fetcher that serves the snapshots of an archive as they
+// were at the current replay time. The replay time only moves forward when
+// Next is called.
+type Player struct {
+	mu        sync.Mutex
+	snapshots map[string][]*Snapshot
+	first     *Snapshot
+	master    *Snapshot
+	now       time.Time
+}
+
+// OpenPlayer reads every snapshot in dir and starts the replay at the time of
+// the oldest one.
+func OpenPlayer(dir string) (*Player, error) {
+	p := &Player{
+		snapshots: make(map[string][]*Snapshot),
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !isSnapshot(info.Name()) {
+			return nil
+		}
+
+		s, err := ReadSnapshot(path)
+
+		if err != nil {
+			return err
+		}
+
+		p.add(s)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.first == nil {
+		return nil, fmt.Errorf("no snapshots found in %s", dir)
+	}
+
+	for _, list := range p.snapshots {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Fetched.Before(list[j].Fetched)
+		})
+	}
+
+	p.now = p.first.Fetched
+
+	return p, nil
+}
+
+// add indexes a snapshot by its url.
+func (p *Player) add(s *Snapshot) {
+	p.snapshots[s.URL] = append(p.snapshots[s.URL], s)
+
+	if p.first == nil || s.Fetched.Before(p.first.Fetched) {
+		p.first = s
+	}
+
+	// The oldest master playlist is where the session started.
+	if strings.Contains(s.Body, "#EXT-X-STREAM-INF") && (p.master == nil || s.Fetched.Before(p.master.Fetched)) {
+		p.master = s
+	}
+}
+
+// URL returns the playlist the recorded session started from, the oldest
+// master playlist or the oldest snapshot when no master was recorded.
+func (p *Player) URL() string {
+	if p.master != nil {
+		return p.master.URL
+	}
+
+	return p.first.URL
+}
+
+// Now returns the current replay time.
+func (p *Player) Now() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.now
+}
+
+// Fetch returns the newest snapshot of url at the replay time. When url was
+// not fetched yet the replay time moves forward to its first snapshot.
+func (p *Player) Fetch(ctx context.Context, url string) (*hls.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	list := p.snapshots[url]
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no snapshots of %s", url)
+	}
+
+	var s *Snapshot
+
+	for _, candidate := range list {
+		if candidate.Fetched.After(p.now) {
+			break
+		}
+
+		s = candidate
+	}
+
+	if s == nil {
+		s = list[0]
+		p.now = s.Fetched
+	}
+
+	resp := s.Response()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// ErrEnd is returned by Next when there are no more snapshots of the urls.
+var ErrEnd = errors.New("end of recording")
+
+// Next moves the replay time to the earliest next snapshot of any of urls
+// and returns how far it moved.
+func (p *Player) Next(urls ...string) (time.Duration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var next *Snapshot
+
+	for _, url := range urls {
+		for _, s := range p.snapshots[url] {
+			if s.Fetched.After(p.now) {
+				if next == nil || s.Fetched.Before(next.Fetched) {
+					next = s
+				}
+
+				break
+			}
+		}
+	}
+
+	if next == nil {
+		return 0, ErrEnd
+	}
+
+	d := next.Fetched.Sub(p.now)
+	p.now = next.Fetched
+
+	return d, nil
+}
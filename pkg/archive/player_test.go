@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"testing"
+	"time"
+)
+
+// start is when the test recordings begin.
+var start = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// newTestPlayer creates a player of snapshots of each url at the seconds
+// after start given, in order.
+func newTestPlayer(seconds map[string][]int) *Player {
+	p := &Player{snapshots: make(map[string][]*Snapshot)}
+
+	for url, list := range seconds {
+		for _, s := range list {
+			p.add(&Snapshot{URL: url, Fetched: start.Add(time.Duration(s) * time.Second), Status: 200})
+		}
+	}
+
+	p.now = p.first.Fetched
+
+	return p
+}
+
+func TestPlayerNext(t *testing.T) {
+	seconds := map[string][]int{
+		"low":  {0, 4, 8},
+		"high": {1, 6},
+	}
+
+	tests := []struct {
+		name  string
+		urls  []string
+		steps []int
+	}{
+		{"one url", []string{"low"}, []int{4, 4}},
+		{"later url", []string{"high"}, []int{1, 5}},
+		{"earliest of both", []string{"low", "high"}, []int{1, 3, 2, 2}},
+		{"unknown url", []string{"audio"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlayer(seconds)
+
+			for i, want := range tt.steps {
+				d, err := p.Next(tt.urls...)
+
+				if err != nil {
+					t.Fatalf("step %d failed: %s", i+1, err)
+				}
+
+				if d != time.Duration(want)*time.Second {
+					t.Errorf("step %d moved %s, want %ds", i+1, d, want)
+				}
+			}
+
+			if d, err := p.Next(tt.urls...); err != ErrEnd {
+				t.Errorf("Next after the last snapshot = %s, %v, want ErrEnd", d, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// Options control how long snapshots are kept. Zero values keep everything.
+type Options struct {
+	// MaxSize is the total size in bytes of the snapshots to keep.
+	MaxSize int64
+
+	// MaxAge is how long to keep a snapshot after it was fetched.
+	MaxAge time.Duration
+}
+
+// file is a snapshot that has been written to the archive.
+type file struct {
+	path    string
+	size    int64
+	fetched time.Time
+}
+
+// Recorder is an hls.Fetcher that saves every response it fetches to a
+// directory, removing the oldest snapshots once the limits are reached.
+type Recorder struct {
+	fetcher hls.Fetcher
+	dir     string
+	opts    Options
+
+	mu    sync.Mutex
+	files []file
+	total int64
+	err   error
+}
+
+// NewRecorder creates a Recorder saving the responses of fetcher in dir. A
+// nil fetcher uses hls.DefaultFetcher. Snapshots already in dir count towards
+// the limits.
+func NewRecorder(fetcher hls.Fetcher, dir string, opts Options) (*Recorder, error) {
+	if fetcher == nil {
+		fetcher = hls.DefaultFetcher
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		fetcher: fetcher,
+		dir:     dir,
+		opts:    opts,
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !isSnapshot(info.Name()) {
+			return nil
+		}
+
+		r.files = append(r.files, file{path: path, size: info.Size(), fetched: info.ModTime()})
+		r.total += info.Size()
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Paths sort by the time they were fetched.
+	sort.Slice(r.files, func(i, j int) bool {
+		return r.files[i].path < r.files[j].path
+	})
+
+	return r, nil
+}
+
+// Fetch fetches url and saves the response. Failing to save does not fail the
+// fetch, the error is available from Err.
+func (r *Recorder) Fetch(ctx context.Context, url string) (*hls.Response, error) {
+	resp, err := r.fetcher.Fetch(ctx, url)
+
+	if resp != nil {
+		if saveErr := r.save(NewSnapshot(resp)); saveErr != nil {
+			r.mu.Lock()
+			r.err = saveErr
+			r.mu.Unlock()
+		}
+	}
+
+	return resp, err
+}
+
+// Err returns the last error hit while saving a snapshot.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// save writes the snapshot and applies the limits.
+func (r *Recorder) save(s *Snapshot) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(r.dir, s.Path())
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.files = append(r.files, file{path: path, size: int64(len(data)), fetched: s.Fetched})
+	r.total += int64(len(data))
+
+	return r.rotate()
+}
+
+// rotate removes the oldest snapshots until the archive is within its limits.
+func (r *Recorder) rotate() error {
+	for len(r.files) > 1 {
+		oldest := r.files[0]
+
+		expired := r.opts.MaxAge > 0 && time.Since(oldest.fetched) > r.opts.MaxAge
+		full := r.opts.MaxSize > 0 && r.total > r.opts.MaxSize
+
+		if !expired && !full {
+			return nil
+		}
+
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		// Remove the day directory once it is empty, this fails otherwise.
+		if dir := filepath.Dir(oldest.path); dir != filepath.Clean(r.dir) {
+			os.Remove(dir)
+		}
+
+		r.files = r.files[1:]
+		r.total -= oldest.size
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// snapshotExt is the extension of every snapshot file.
+const snapshotExt = ".json"
+
+// Snapshot is a single playlist response saved to disk.
+type Snapshot struct {
+	URL       string      `json:"url"`
+	Fetched   time.Time   `json:"fetched"`
+	LatencyMs float64     `json:"latency_ms"`
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header"`
+	Body      string      `json:"body"`
+}
+
+// NewSnapshot creates a snapshot of a response.
+func NewSnapshot(resp *hls.Response) *Snapshot {
+	return &Snapshot{
+		URL:       resp.URL,
+		Fetched:   resp.Fetched,
+		LatencyMs: float64(resp.Latency) / float64(time.Millisecond),
+		Status:    resp.StatusCode,
+		Header:    resp.Header,
+		Body:      string(resp.Body),
+	}
+}
+
+// Response turns the snapshot back into a response.
+func (s *Snapshot) Response() *hls.Response {
+	return &hls.Response{
+		URL:        s.URL,
+		StatusCode: s.Status,
+		Header:     s.Header,
+		Body:       []byte(s.Body),
+		Fetched:    s.Fetched,
+		Latency:    time.Duration(s.LatencyMs * float64(time.Millisecond)),
+	}
+}
+
+// Path returns the path of the snapshot relative to the archive directory.
+// Snapshots are grouped by day and sort by the time they were fetched, the
+// hash of the url makes it easy to find every snapshot of one playlist.
+func (s *Snapshot) Path() string {
+	h := fnv.New32a()
+	h.Write([]byte(s.URL))
+
+	fetched := s.Fetched.UTC()
+
+	return filepath.Join(
+		fetched.Format("20060102"),
+		fmt.Sprintf("%s-%08x%s", fetched.Format("150405.000000000"), h.Sum32(), snapshotExt),
+	)
+}
+
+// ReadSnapshot reads a snapshot file.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// isSnapshot checks if the file name looks like a snapshot.
+func isSnapshot(name string) bool {
+	return strings.HasSuffix(name, snapshotExt)
+}
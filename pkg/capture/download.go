@@ -0,0 +1,331 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// DownloadOptions configure a Downloader.
+type DownloadOptions struct {
+	// Dir is the directory to download to.
+	Dir string
+
+	// Workers is the number of segments downloaded at once, defaults to 4.
+	Workers int
+
+	// Concat joins the segments into a single file once they are all
+	// downloaded, named after the directory of the rendition.
+	Concat bool
+
+	// Client downloads the segments, http.DefaultClient is used when nil.
+	Client *http.Client
+
+	// Log receives a line for every file downloaded and every error, it may
+	// be nil.
+	Log io.Writer
+}
+
+// job is a single file to download.
+type job struct {
+	url       string
+	byteRange *hls.ByteRange
+	file      string
+}
+
+// Downloader downloads every segment of a VOD variant, skipping files that are
+// already complete so an interrupted download can be resumed.
+type Downloader struct {
+	opts    DownloadOptions
+	variant *hls.Variant
+	mu      sync.Mutex
+}
+
+// NewDownloader prepares to download the variant of master at index.
+func NewDownloader(master *hls.Master, index int, opts DownloadOptions) (*Downloader, error) {
+	variant, err := master.GetVariant(index)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	if opts.Log == nil {
+		opts.Log = ioutil.Discard
+	}
+
+	return &Downloader{
+		opts:    opts,
+		variant: variant,
+	}, nil
+}
+
+// Run downloads the playlist, its initialization sections and segments and
+// writes a local copy of the playlist.
+func (d *Downloader) Run(ctx context.Context) error {
+	u := d.variant.Reload(ctx)
+
+	if u.Err != nil {
+		return u.Err
+	}
+
+	p := u.Playlist
+
+	if !p.EndList {
+		return errors.New("playlist has no EXT-X-ENDLIST, use record for live playlists")
+	}
+
+	// The track is named for the file the segments are joined into.
+	t := newTrack(joinedName(d.variant.URL), d.variant)
+	t.target = p.TargetDuration
+	t.version = p.Version
+
+	jobs := make([]job, 0, len(p.Segments))
+
+	for _, s := range p.Segments {
+		e := entry{
+			sequence:      s.Sequence,
+			duration:      s.Duration,
+			file:          fmt.Sprintf("%d%s", s.Sequence, extension(s.URI)),
+			pdt:           s.ProgramDateTime,
+			discontinuity: s.Discontinuity,
+			gap:           s.Gap,
+			key:           absoluteKey(p, s.Key),
+		}
+
+		if s.Map != nil {
+			key := s.Map.URI
+
+			if s.Map.ByteRange != nil {
+				key = fmt.Sprintf("%s@%d-%d", s.Map.URI, s.Map.ByteRange.Offset, s.Map.ByteRange.Length)
+			}
+
+			file, ok := t.maps[key]
+
+			if !ok {
+				file = fmt.Sprintf("init-%d%s", len(t.maps), extension(s.Map.URI))
+				t.maps[key] = file
+
+				jobs = append(jobs, job{url: p.ResolveURI(s.Map.URI), byteRange: s.Map.ByteRange, file: file})
+			}
+
+			e.mapFile = file
+		}
+
+		if !e.gap {
+			jobs = append(jobs, job{url: p.ResolveURI(s.URI), byteRange: s.ByteRange, file: e.file})
+		}
+
+		t.entries = append(t.entries, e)
+	}
+
+	if err := d.download(ctx, jobs); err != nil {
+		return err
+	}
+
+	if err := writeFile(filepath.Join(d.opts.Dir, playlistName), t.playlist(true)); err != nil {
+		return err
+	}
+
+	if d.opts.Concat {
+		return d.concat(t)
+	}
+
+	return nil
+}
+
+// download runs the jobs on the worker pool and returns the first error once
+// every job has been tried.
+func (d *Downloader) download(ctx context.Context, jobs []job) error {
+	queue := make(chan job)
+	failed := 0
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < d.opts.Workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range queue {
+				if err := d.fetch(ctx, j); err != nil {
+					d.mu.Lock()
+					failed++
+					d.mu.Unlock()
+
+					d.logf("%s %s", j.file, err)
+				}
+			}
+		}()
+	}
+
+	for _, j := range jobs {
+		select {
+		case queue <- j:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed, run again to resume", failed, len(jobs))
+	}
+
+	return nil
+}
+
+// fetch downloads a single file unless it is already complete.
+func (d *Downloader) fetch(ctx context.Context, j job) error {
+	file := filepath.Join(d.opts.Dir, j.file)
+
+	if info, err := os.Stat(file); err == nil {
+		if complete, err := d.complete(ctx, j, info.Size()); err != nil {
+			return err
+		} else if complete {
+			d.logf("%s already downloaded", j.file)
+			return nil
+		}
+	}
+
+	start := time.Now()
+
+	n, err := saveFile(ctx, d.opts.Client, j.url, j.byteRange, file)
+
+	if err != nil {
+		return err
+	}
+
+	d.logf("%s saved %d bytes in %s", j.file, n, time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
+
+// complete checks if an existing file has the size of the resource. Byte
+// ranges carry their size, otherwise the server is asked for it.
+func (d *Downloader) complete(ctx context.Context, j job, size int64) (bool, error) {
+	if j.byteRange != nil {
+		return size == j.byteRange.Length, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, j.url, nil)
+
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := d.opts.Client.Do(req)
+
+	if err != nil {
+		return false, err
+	}
+
+	resp.Body.Close()
+
+	// Without a length there is nothing to compare, trust the file since it
+	// was only moved into place once it was fully written.
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return true, nil
+	}
+
+	return size == resp.ContentLength, nil
+}
+
+// concat joins the downloaded segments in playlist order into one file. Only
+// formats that can be played back to back, such as MPEG-TS, make sense here.
+func (d *Downloader) concat(t *track) error {
+	entries := make([]entry, 0, len(t.entries))
+
+	for _, e := range t.entries {
+		if !e.gap {
+			entries = append(entries, e)
+		}
+	}
+
+	if len(entries) == 0 {
+		return errors.New("no segments to join")
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].sequence < entries[j].sequence
+	})
+
+	name := filepath.Join(d.opts.Dir, fmt.Sprintf("%s%s", t.name, extension(entries[0].file)))
+
+	out, err := os.Create(name)
+
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+
+	mapFile := ""
+
+	for _, e := range entries {
+		// Fragmented MP4 needs its initialization section up front.
+		if e.mapFile != "" && e.mapFile != mapFile {
+			mapFile = e.mapFile
+
+			if err := appendFile(out, filepath.Join(d.opts.Dir, mapFile)); err != nil {
+				return err
+			}
+		}
+
+		if err := appendFile(out, filepath.Join(d.opts.Dir, e.file)); err != nil {
+			return err
+		}
+	}
+
+	d.logf("joined %d segments into %s", len(entries), name)
+
+	return out.Close()
+}
+
+func appendFile(w io.Writer, file string) error {
+	f, err := os.Open(file)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+
+	return err
+}
+
+func (d *Downloader) logf(format string, args ...interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fmt.Fprintf(d.opts.Log, "%s %s\n", time.Now().UTC().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,505 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// playlistName is the name of every playlist written by the DVR.
+const playlistName = "index.m3u8"
+
+// DVROptions configure a DVR.
+type DVROptions struct {
+	// Dir is the directory to write the recording to.
+	Dir string
+
+	// Interval is the time to wait between reloads, half the target duration
+	// is used when it is zero.
+	Interval time.Duration
+
+	// Client downloads the segments, http.DefaultClient is used when nil.
+	Client *http.Client
+
+	// Log receives a line for every segment saved and every error, it may be
+	// nil.
+	Log io.Writer
+}
+
+// entry is a segment in a local playlist.
+type entry struct {
+	sequence      int
+	duration      float64
+	file          string
+	mapFile       string
+	key           string
+	pdt           time.Time
+	discontinuity bool
+	gap           bool
+}
+
+// track is a single rendition being recorded.
+type track struct {
+	name     string
+	variant  *hls.Variant
+	entries  []entry
+	maps     map[string]string
+	last     int
+	target   int
+	version  int
+	ended    bool
+	reloaded time.Time
+
+	// restarts counts the times the media sequence started over, it keeps
+	// the files of each run apart.
+	restarts int
+}
+
+// DVR records a live variant, and the audio and subtitle renditions it
+// references, into a local VOD playlist.
+type DVR struct {
+	opts   DVROptions
+	master *hls.Master
+	tracks []*track
+	mu     sync.Mutex
+}
+
+// NewDVR prepares to record the variant of master at index.
+func NewDVR(master *hls.Master, index int, opts DVROptions) (*DVR, error) {
+	variant, err := master.GetVariant(index)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	if opts.Log == nil {
+		opts.Log = ioutil.Discard
+	}
+
+	d := &DVR{
+		opts:   opts,
+		master: master,
+	}
+
+	d.tracks = append(d.tracks, newTrack("video", variant))
+
+	if variant.Type != "" {
+		d.tracks[0].name = safeName(strings.ToLower(variant.Type))
+		return d, nil
+	}
+
+	// Follow the renditions in the groups the variant references.
+	names := map[string]bool{}
+
+	for _, rendition := range master.Variants {
+		group, ok := variant.Attributes[rendition.Type]
+
+		if !ok || (rendition.Type != "AUDIO" && rendition.Type != "SUBTITLES") || rendition.Attributes["GROUP-ID"] != group {
+			continue
+		}
+
+		name := safeName(rendition.Type + "-" + rendition.Attributes["NAME"])
+
+		for i := 2; names[name]; i++ {
+			name = safeName(fmt.Sprintf("%s-%s-%d", rendition.Type, rendition.Attributes["NAME"], i))
+		}
+
+		names[name] = true
+
+		d.tracks = append(d.tracks, newTrack(name, rendition))
+	}
+
+	return d, nil
+}
+
+func newTrack(name string, variant *hls.Variant) *track {
+	return &track{
+		name:    name,
+		variant: variant,
+		maps:    make(map[string]string),
+		last:    -1,
+	}
+}
+
+// Run records until ctx is cancelled or every playlist has ended, then writes
+// the final playlists.
+func (d *DVR) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	for _, t := range d.tracks {
+		wg.Add(1)
+
+		go func(t *track) {
+			defer wg.Done()
+			d.follow(ctx, t)
+		}(t)
+	}
+
+	wg.Wait()
+
+	return d.write(true)
+}
+
+// follow reloads the playlist of a track and saves its new segments.
+func (d *DVR) follow(ctx context.Context, t *track) {
+	for {
+		u := t.variant.Reload(ctx)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		interval := d.opts.Interval
+
+		if u.Err != nil {
+			d.logf("%s %s", t.name, u.Err)
+		} else {
+			d.capture(ctx, t, u.Playlist)
+
+			if err := d.write(false); err != nil {
+				d.logf("%s %s", t.name, err)
+			}
+
+			if interval == 0 {
+				interval = time.Duration(u.Playlist.TargetDuration) * time.Second / 2
+			}
+		}
+
+		if t.ended {
+			return
+		}
+
+		if interval <= 0 {
+			interval = time.Second
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// capture saves the segments of p that have not been seen yet.
+func (d *DVR) capture(ctx context.Context, t *track, p *hls.MediaPlaylist) {
+	d.mu.Lock()
+	if p.TargetDuration > t.target {
+		t.target = p.TargetDuration
+	}
+
+	if p.Version > t.version {
+		t.version = p.Version
+	}
+	d.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(t.reloaded)
+	t.reloaded = now
+
+	// A media sequence that goes back, or moves on by more than could have
+	// been published since the last reload, is an encoder that restarted.
+	// It is recorded from its new start after a discontinuity rather than
+	// filled with gaps.
+	restart := false
+
+	if n := len(p.Segments); n > 0 && t.last >= 0 {
+		first, final := p.Segments[0].Sequence, p.Segments[n-1].Sequence
+
+		if final < t.last || first-t.last-1 > maxMissed(elapsed, p.TargetDuration) {
+			d.logf("%s media sequence moved from %d to %d, recording it as a restart", t.name, t.last, first)
+
+			t.last = -1
+			t.restarts++
+			restart = true
+		}
+	}
+
+	for _, s := range p.Segments {
+		if s.Sequence <= t.last {
+			continue
+		}
+
+		// Segments that left the playlist before we could reload it are gaps.
+		if t.last >= 0 {
+			for seq := t.last + 1; seq < s.Sequence; seq++ {
+				d.add(t, entry{
+					sequence: seq,
+					duration: float64(p.TargetDuration),
+					file:     t.file(seq, ".gap"+extension(s.URI)),
+					gap:      true,
+				})
+
+				d.logf("%s segment %d missed", t.name, seq)
+			}
+		}
+
+		e := entry{
+			sequence:      s.Sequence,
+			duration:      s.Duration,
+			file:          t.file(s.Sequence, extension(s.URI)),
+			pdt:           s.ProgramDateTime,
+			discontinuity: s.Discontinuity || restart,
+			gap:           s.Gap,
+			key:           absoluteKey(p, s.Key),
+		}
+
+		if s.Map != nil {
+			file, err := d.saveMap(ctx, t, p, s.Map)
+
+			if err != nil {
+				d.logf("%s %s", t.name, err)
+			}
+
+			e.mapFile = file
+		}
+
+		if !e.gap {
+			n, err := saveFile(ctx, d.opts.Client, p.ResolveURI(s.URI), s.ByteRange, filepath.Join(d.opts.Dir, t.name, e.file))
+
+			if err != nil {
+				e.gap = true
+				d.logf("%s segment %d %s", t.name, s.Sequence, err)
+			} else {
+				d.logf("%s segment %d saved %d bytes", t.name, s.Sequence, n)
+			}
+		}
+
+		d.add(t, e)
+
+		t.last = s.Sequence
+		restart = false
+	}
+
+	if p.EndList {
+		t.ended = true
+	}
+}
+
+// maxMissed returns the most segments that could have been published and
+// slid out of the playlist in elapsed. Segments are rarely shorter than half
+// the target duration, so twice as many as the target duration allows are
+// counted.
+func maxMissed(elapsed time.Duration, targetDuration int) int {
+	if targetDuration < 1 {
+		targetDuration = 1
+	}
+
+	return int(elapsed*2/(time.Duration(targetDuration)*time.Second)) + 1
+}
+
+// file names the file of the segment at seq, apart from those of earlier runs
+// of the stream when its media sequence started over.
+func (t *track) file(seq int, ext string) string {
+	if t.restarts > 0 {
+		return fmt.Sprintf("%d-%d%s", t.restarts, seq, ext)
+	}
+
+	return fmt.Sprintf("%d%s", seq, ext)
+}
+
+// saveMap saves an initialization section once and returns its file name.
+func (d *DVR) saveMap(ctx context.Context, t *track, p *hls.MediaPlaylist, m *hls.Map) (string, error) {
+	key := m.URI
+
+	if m.ByteRange != nil {
+		key = fmt.Sprintf("%s@%d-%d", m.URI, m.ByteRange.Offset, m.ByteRange.Length)
+	}
+
+	if file, ok := t.maps[key]; ok {
+		return file, nil
+	}
+
+	file := fmt.Sprintf("init-%d%s", len(t.maps), extension(m.URI))
+
+	if _, err := saveFile(ctx, d.opts.Client, p.ResolveURI(m.URI), m.ByteRange, filepath.Join(d.opts.Dir, t.name, file)); err != nil {
+		return file, err
+	}
+
+	t.maps[key] = file
+
+	return file, nil
+}
+
+func (d *DVR) add(t *track, e entry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t.entries = append(t.entries, e)
+}
+
+func (d *DVR) logf(format string, args ...interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fmt.Fprintf(d.opts.Log, "%s %s\n", time.Now().UTC().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+// write writes the playlist of every track and a master playlist that ties
+// them together. Until final is set the playlists are EVENT playlists.
+func (d *DVR) write(final bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, t := range d.tracks {
+		if err := writeFile(filepath.Join(d.opts.Dir, t.name, playlistName), t.playlist(final)); err != nil {
+			return err
+		}
+	}
+
+	return writeFile(filepath.Join(d.opts.Dir, playlistName), d.masterPlaylist())
+}
+
+// playlist builds the local media playlist of the track.
+func (t *track) playlist(final bool) string {
+	output := new(bytes.Buffer)
+
+	target := t.target
+
+	for _, e := range t.entries {
+		if d := int(math.Ceil(e.duration)); d > target {
+			target = d
+		}
+	}
+
+	fmt.Fprint(output, "#EXTM3U\n")
+
+	if t.version > 0 {
+		fmt.Fprintf(output, "#EXT-X-VERSION:%d\n", t.version)
+	}
+
+	fmt.Fprintf(output, "#EXT-X-TARGETDURATION:%d\n", target)
+
+	if len(t.entries) > 0 {
+		fmt.Fprintf(output, "#EXT-X-MEDIA-SEQUENCE:%d\n", t.entries[0].sequence)
+	}
+
+	if final {
+		fmt.Fprint(output, "#EXT-X-PLAYLIST-TYPE:VOD\n")
+	} else {
+		fmt.Fprint(output, "#EXT-X-PLAYLIST-TYPE:EVENT\n")
+	}
+
+	var key, mapFile string
+
+	for _, e := range t.entries {
+		if e.discontinuity {
+			fmt.Fprint(output, "#EXT-X-DISCONTINUITY\n")
+		}
+
+		if e.key != key && !e.gap {
+			key = e.key
+
+			if key == "" {
+				fmt.Fprint(output, "#EXT-X-KEY:METHOD=NONE\n")
+			} else {
+				fmt.Fprintf(output, "%s\n", key)
+			}
+		}
+
+		if e.mapFile != "" && e.mapFile != mapFile {
+			mapFile = e.mapFile
+			fmt.Fprintf(output, "#EXT-X-MAP:URI=\"%s\"\n", mapFile)
+		}
+
+		if !e.pdt.IsZero() {
+			fmt.Fprintf(output, "#EXT-X-PROGRAM-DATE-TIME:%s\n", e.pdt.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+		}
+
+		fmt.Fprintf(output, "#EXTINF:%.3f,\n", e.duration)
+
+		if e.gap {
+			fmt.Fprint(output, "#EXT-X-GAP\n")
+		}
+
+		fmt.Fprintf(output, "%s\n", e.file)
+	}
+
+	if final {
+		fmt.Fprint(output, "#EXT-X-ENDLIST\n")
+	}
+
+	return output.String()
+}
+
+// masterPlaylist builds a master playlist pointing at the local playlists.
+func (d *DVR) masterPlaylist() string {
+	output := new(bytes.Buffer)
+
+	fmt.Fprint(output, "#EXTM3U\n")
+
+	video := d.tracks[0]
+
+	for _, t := range d.tracks[1:] {
+		fmt.Fprintf(output, "%s\n", replaceURI(t.variant.StreamInf(), filepath.ToSlash(filepath.Join(t.name, playlistName))))
+	}
+
+	if video.variant.Type != "" {
+		fmt.Fprintf(output, "%s\n", replaceURI(video.variant.StreamInf(), filepath.ToSlash(filepath.Join(video.name, playlistName))))
+		return output.String()
+	}
+
+	if inf := video.variant.StreamInf(); inf != "" {
+		fmt.Fprintf(output, "%s\n", inf)
+	} else {
+		fmt.Fprint(output, "#EXT-X-STREAM-INF:BANDWIDTH=0\n")
+	}
+
+	fmt.Fprintf(output, "%s/%s\n", video.name, playlistName)
+
+	return output.String()
+}
+
+// absoluteKey rewrites the uri of an EXT-X-KEY tag to be absolute so the
+// local playlist can still find the key.
+func absoluteKey(p *hls.MediaPlaylist, key string) string {
+	if key == "" {
+		return ""
+	}
+
+	i := strings.Index(key, ":")
+	attrs := hls.ParseAttributes(key[i+1:])
+
+	if attrs["METHOD"] == "NONE" {
+		return ""
+	}
+
+	uri, ok := attrs["URI"]
+
+	if !ok {
+		return key
+	}
+
+	return replaceURI(key, p.ResolveURI(uri))
+}
+
+// replaceURI replaces the URI attribute of a tag.
+func replaceURI(tag string, uri string) string {
+	i := strings.Index(tag, "URI=\"")
+
+	if i < 0 {
+		return tag
+	}
+
+	end := strings.Index(tag[i+5:], "\"")
+
+	if end < 0 {
+		return tag
+	}
+
+	return fmt.Sprintf("%s%s%s", tag[:i+5], uri, tag[i+5+end:])
+}
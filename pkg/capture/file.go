@@ -0,0 +1,157 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// saveFile downloads url, or the byte range of it, to file. The body is
+// written next to file first and only moved into place once its size has been
+// verified, so an existing file is always complete.
+func saveFile(ctx context.Context, client *http.Client, url string, r *hls.ByteRange, file string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if r != nil {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.Offset, r.Offset+r.Length-1))
+	}
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+
+	// Without a partial response the range has to be cut out of the body.
+	body := io.Reader(resp.Body)
+	expected := resp.ContentLength
+
+	if r != nil {
+		if resp.StatusCode != http.StatusPartialContent {
+			if _, err := io.CopyN(ioutil.Discard, resp.Body, r.Offset); err != nil {
+				return 0, fmt.Errorf("%s: %w", url, err)
+			}
+
+			body = io.LimitReader(resp.Body, r.Length)
+		}
+
+		expected = r.Length
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return 0, err
+	}
+
+	part := file + ".part"
+
+	f, err := os.Create(part)
+
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(f, body)
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil && expected >= 0 && n != expected {
+		err = fmt.Errorf("%s: expected %d bytes but got %d", url, expected, n)
+	}
+
+	if err != nil {
+		os.Remove(part)
+		return n, err
+	}
+
+	return n, os.Rename(part, file)
+}
+
+// writeFile writes a playlist, replacing the previous one in a single step so
+// readers never see half of it.
+func writeFile(file string, data string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	part := file + ".part"
+
+	f, err := os.Create(part)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(f, data)
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(part)
+		return err
+	}
+
+	return os.Rename(part, file)
+}
+
+// extension returns the file extension of a segment uri, defaulting to .ts.
+func extension(uri string) string {
+	if i := strings.IndexAny(uri, "?#"); i >= 0 {
+		uri = uri[:i]
+	}
+
+	ext := path.Ext(uri)
+
+	if ext == "" {
+		return ".ts"
+	}
+
+	return ext
+}
+
+var unsafeName = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// safeName turns a rendition name into something usable as a directory.
+func safeName(name string) string {
+	return strings.Trim(unsafeName.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// joinedName names the file segments are joined into after the playlist at
+// uri, e.g. video for video.m3u8. A name a segment or an initialization section
+// could have is replaced by joined.
+func joinedName(uri string) string {
+	if i := strings.IndexAny(uri, "?#"); i >= 0 {
+		uri = uri[:i]
+	}
+
+	base := path.Base(uri)
+	name := safeName(strings.TrimSuffix(base, path.Ext(base)))
+
+	if strings.Trim(name, "0123456789") == "" || strings.HasPrefix(name, "init-") {
+		return "joined"
+	}
+
+	return name
+}
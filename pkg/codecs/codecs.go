@@ -0,0 +1,563 @@
+// Package codecs decodes the RFC 6381 codec strings found in the CODECS
+// attribute of HLS master playlists, such as avc1.64001f or hvc1.2.4.L123.B0.
+package codecs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kinds of media a codec carries.
+const (
+	KindVideo     = "video"
+	KindAudio     = "audio"
+	KindSubtitles = "subtitles"
+)
+
+// Tiers of HEVC and AV1.
+const (
+	TierMain = "Main"
+	TierHigh = "High"
+)
+
+// Codec is a decoded codec string.
+type Codec struct {
+	// Raw is the codec string as written.
+	Raw string
+
+	// Kind is the media the codec carries, video, audio or subtitles.
+	Kind string
+
+	// Name is the common name of the codec, e.g. H.264 or AAC-LC.
+	Name string
+
+	// Profile, Tier and Level are those of a video codec, empty when the
+	// codec string does not give them. Level is the level number, e.g. 3.1.
+	Profile string
+	Tier    string
+	Level   string
+
+	// Details holds anything else the codec string says, such as the bit
+	// depth.
+	Details []string
+}
+
+// String describes the codec, e.g. "H.264 High L3.1". The tier is only
+// given when it is the high tier, most streams use the main tier.
+func (c *Codec) String() string {
+	parts := []string{c.Name}
+
+	if c.Profile != "" {
+		parts = append(parts, c.Profile)
+	}
+
+	if c.Tier == TierHigh {
+		parts = append(parts, "High tier")
+	}
+
+	if c.Level != "" {
+		parts = append(parts, "L"+c.Level)
+	}
+
+	parts = append(parts, c.Details...)
+
+	return strings.Join(parts, " ")
+}
+
+// decoder decodes the fields of a codec string after its four character
+// code.
+type decoder func(c *Codec, fields []string) error
+
+// fourCCs are the codecs known by their four character code, the first
+// field of the codec string.
+var fourCCs = map[string]struct {
+	kind   string
+	name   string
+	decode decoder
+}{
+	"avc1": {KindVideo, "H.264", decodeAVC},
+	"avc3": {KindVideo, "H.264", decodeAVC},
+	"hvc1": {KindVideo, "HEVC", decodeHEVC},
+	"hev1": {KindVideo, "HEVC", decodeHEVC},
+	"dvh1": {KindVideo, "Dolby Vision", decodeDolbyVision},
+	"dvhe": {KindVideo, "Dolby Vision", decodeDolbyVision},
+	"dva1": {KindVideo, "Dolby Vision", decodeDolbyVision},
+	"dvav": {KindVideo, "Dolby Vision", decodeDolbyVision},
+	"av01": {KindVideo, "AV1", decodeAV1},
+	"vp09": {KindVideo, "VP9", decodeVP9},
+	"vp08": {KindVideo, "VP8", nil},
+	"mp4a": {KindAudio, "AAC", decodeMP4A},
+	"ac-3": {KindAudio, "Dolby Digital (AC-3)", nil},
+	"ec-3": {KindAudio, "Dolby Digital Plus (E-AC-3)", nil},
+	"ac-4": {KindAudio, "Dolby AC-4", nil},
+	"Opus": {KindAudio, "Opus", nil},
+	"opus": {KindAudio, "Opus", nil},
+	"fLaC": {KindAudio, "FLAC", nil},
+	"alac": {KindAudio, "ALAC", nil},
+	"stpp": {KindSubtitles, "TTML", decodeSTPP},
+	"wvtt": {KindSubtitles, "WebVTT", nil},
+}
+
+// Parse decodes a single codec string. The error says what is wrong with
+// one that is malformed or not known.
+func Parse(codec string) (*Codec, error) {
+	codec = strings.TrimSpace(codec)
+	fields := strings.Split(codec, ".")
+
+	known, ok := fourCCs[fields[0]]
+
+	if !ok {
+		return nil, fmt.Errorf("%q: unknown codec %q", codec, fields[0])
+	}
+
+	c := &Codec{Raw: codec, Kind: known.kind, Name: known.name}
+
+	if known.decode == nil {
+		if len(fields) > 1 {
+			return nil, fmt.Errorf("%q: %s takes no parameters", codec, fields[0])
+		}
+
+		return c, nil
+	}
+
+	if err := known.decode(c, fields[1:]); err != nil {
+		return nil, fmt.Errorf("%q: %s", codec, err)
+	}
+
+	return c, nil
+}
+
+// ParseList decodes every codec of a CODECS attribute. Those that fail are
+// left out and their errors returned.
+func ParseList(codecs string) ([]*Codec, []error) {
+	var list []*Codec
+	var errs []error
+
+	for _, codec := range strings.Split(codecs, ",") {
+		if strings.TrimSpace(codec) == "" {
+			continue
+		}
+
+		c, err := Parse(codec)
+
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		list = append(list, c)
+	}
+
+	return list, errs
+}
+
+// Describe describes every codec of a CODECS attribute, separated by commas.
+// Codecs that can't be decoded are given as written with a question mark.
+func Describe(codecs string) string {
+	var names []string
+
+	for _, codec := range strings.Split(codecs, ",") {
+		codec = strings.TrimSpace(codec)
+
+		if codec == "" {
+			continue
+		}
+
+		c, err := Parse(codec)
+
+		if err != nil {
+			names = append(names, codec+"?")
+			continue
+		}
+
+		names = append(names, c.String())
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// Validate returns what is wrong with each codec of a CODECS attribute, or
+// nothing when they all decode.
+func Validate(codecs string) []error {
+	if strings.TrimSpace(codecs) == "" {
+		return []error{fmt.Errorf("no codecs given")}
+	}
+
+	_, errs := ParseList(codecs)
+
+	return errs
+}
+
+// avcProfiles name the H.264 profiles by profile_idc.
+var avcProfiles = map[int]string{
+	66:  "Baseline",
+	77:  "Main",
+	88:  "Extended",
+	100: "High",
+	110: "High 10",
+	122: "High 4:2:2",
+	244: "High 4:4:4",
+	44:  "CAVLC 4:4:4",
+	83:  "Scalable Baseline",
+	86:  "Scalable High",
+	118: "Multiview High",
+	128: "Stereo High",
+}
+
+// decodeAVC decodes avc1.PPCCLL, the profile, constraint flags and level as
+// hex bytes. The older avc1.PPP.LL form with decimal numbers is understood
+// too.
+func decodeAVC(c *Codec, fields []string) error {
+	var profile, constraints, level int
+
+	switch {
+	case len(fields) == 1 && len(fields[0]) == 6:
+		b, err := hexBytes(fields[0])
+
+		if err != nil {
+			return err
+		}
+
+		profile, constraints, level = int(b[0]), int(b[1]), int(b[2])
+	case len(fields) == 2:
+		var err error
+
+		if profile, err = strconv.Atoi(fields[0]); err != nil {
+			return fmt.Errorf("profile %q is not a number", fields[0])
+		}
+
+		if level, err = strconv.Atoi(fields[1]); err != nil {
+			return fmt.Errorf("level %q is not a number", fields[1])
+		}
+	default:
+		return fmt.Errorf("expected six hex digits of profile, constraints and level")
+	}
+
+	name, ok := avcProfiles[profile]
+
+	if !ok {
+		return fmt.Errorf("unknown profile %d", profile)
+	}
+
+	// Baseline with constraint_set1 is the constrained baseline of most
+	// mobile encoders.
+	if profile == 66 && constraints&0x40 != 0 {
+		name = "Constrained Baseline"
+	}
+
+	c.Profile = name
+
+	// Level 1b is signalled as 11 with constraint_set3 in Baseline, Main and
+	// Extended, and as 9 in the other profiles.
+	if level == 9 || level == 11 && constraints&0x10 != 0 && profile <= 88 {
+		c.Level = "1b"
+		return nil
+	}
+
+	if level < 10 || level > 62 {
+		return fmt.Errorf("unknown level %d", level)
+	}
+
+	c.Level = fmt.Sprintf("%d.%d", level/10, level%10)
+
+	return nil
+}
+
+// hevcProfiles name the HEVC profiles by general_profile_idc.
+var hevcProfiles = map[int]string{
+	1: "Main",
+	2: "Main 10",
+	3: "Main Still Picture",
+	4: "Range Extensions",
+	5: "High Throughput",
+	9: "Screen Content",
+}
+
+// decodeHEVC decodes hvc1.[A-C]P.C.[LH]L.B..., the profile space and profile,
+// the compatibility flags in hex, the tier and level, and up to six
+// constraint bytes.
+func decodeHEVC(c *Codec, fields []string) error {
+	if len(fields) < 3 {
+		return fmt.Errorf("expected profile, compatibility flags and tier and level")
+	}
+
+	if len(fields) > 9 {
+		return fmt.Errorf("more than six constraint bytes")
+	}
+
+	profile := fields[0]
+
+	// A profile space other than 0 is written as a letter in front.
+	if profile != "" && profile[0] >= 'A' && profile[0] <= 'C' {
+		profile = profile[1:]
+	}
+
+	idc, err := strconv.Atoi(profile)
+
+	if err != nil {
+		return fmt.Errorf("profile %q is not a number", fields[0])
+	}
+
+	name, ok := hevcProfiles[idc]
+
+	if !ok {
+		return fmt.Errorf("unknown profile %d", idc)
+	}
+
+	if _, err := strconv.ParseUint(fields[1], 16, 32); err != nil {
+		return fmt.Errorf("compatibility flags %q are not hex", fields[1])
+	}
+
+	tierLevel := fields[2]
+
+	if len(tierLevel) < 2 || (tierLevel[0] != 'L' && tierLevel[0] != 'H') {
+		return fmt.Errorf("tier and level %q should be L or H and a number", tierLevel)
+	}
+
+	level, err := strconv.Atoi(tierLevel[1:])
+
+	if err != nil || level%3 != 0 {
+		return fmt.Errorf("level %q is not a multiple of 3", tierLevel[1:])
+	}
+
+	for _, b := range fields[3:] {
+		if _, err := strconv.ParseUint(b, 16, 8); err != nil {
+			return fmt.Errorf("constraint byte %q is not hex", b)
+		}
+	}
+
+	c.Profile = name
+	c.Tier = TierMain
+
+	if tierLevel[0] == 'H' {
+		c.Tier = TierHigh
+	}
+
+	// Levels are 30 times the level number.
+	c.Level = fmt.Sprintf("%d.%d", level/30, level%30/3)
+
+	return nil
+}
+
+// decodeDolbyVision decodes dvh1.PP.LL, the profile and level.
+func decodeDolbyVision(c *Codec, fields []string) error {
+	if len(fields) != 2 {
+		return fmt.Errorf("expected profile and level")
+	}
+
+	profile, err := strconv.Atoi(fields[0])
+
+	if err != nil {
+		return fmt.Errorf("profile %q is not a number", fields[0])
+	}
+
+	level, err := strconv.Atoi(fields[1])
+
+	if err != nil || level < 1 || level > 13 {
+		return fmt.Errorf("level %q is not from 1 to 13", fields[1])
+	}
+
+	c.Profile = fmt.Sprintf("profile %d", profile)
+	c.Level = strconv.Itoa(level)
+
+	return nil
+}
+
+// av1Profiles name the AV1 profiles.
+var av1Profiles = map[string]string{
+	"0": "Main",
+	"1": "High",
+	"2": "Professional",
+}
+
+// decodeAV1 decodes av01.P.LLT.DD followed by optional colour fields, the
+// profile, sequence level index and tier, and bit depth.
+func decodeAV1(c *Codec, fields []string) error {
+	if len(fields) < 3 {
+		return fmt.Errorf("expected profile, level and tier, and bit depth")
+	}
+
+	name, ok := av1Profiles[fields[0]]
+
+	if !ok {
+		return fmt.Errorf("unknown profile %q", fields[0])
+	}
+
+	levelTier := fields[1]
+
+	if len(levelTier) != 3 || (levelTier[2] != 'M' && levelTier[2] != 'H') {
+		return fmt.Errorf("level and tier %q should be two digits and M or H", levelTier)
+	}
+
+	index, err := strconv.Atoi(levelTier[:2])
+
+	if err != nil || index > 31 {
+		return fmt.Errorf("level %q is not from 00 to 31", levelTier[:2])
+	}
+
+	depth, err := strconv.Atoi(fields[2])
+
+	if err != nil || (depth != 8 && depth != 10 && depth != 12) {
+		return fmt.Errorf("bit depth %q is not 08, 10 or 12", fields[2])
+	}
+
+	c.Profile = name
+	c.Tier = TierMain
+
+	if levelTier[2] == 'H' {
+		c.Tier = TierHigh
+	}
+
+	// The sequence level index counts four minor levels from level 2.0.
+	c.Level = fmt.Sprintf("%d.%d", 2+index/4, index%4)
+	c.Details = append(c.Details, fmt.Sprintf("%d-bit", depth))
+
+	return nil
+}
+
+// decodeVP9 decodes vp09.PP.LL.DD followed by optional colour fields, the
+// profile, level and bit depth.
+func decodeVP9(c *Codec, fields []string) error {
+	if len(fields) < 3 {
+		return fmt.Errorf("expected profile, level and bit depth")
+	}
+
+	profile, err := strconv.Atoi(fields[0])
+
+	if err != nil || profile > 3 {
+		return fmt.Errorf("profile %q is not from 00 to 03", fields[0])
+	}
+
+	level, err := strconv.Atoi(fields[1])
+
+	if err != nil || level < 10 || level > 62 {
+		return fmt.Errorf("level %q is not from 10 to 62", fields[1])
+	}
+
+	depth, err := strconv.Atoi(fields[2])
+
+	if err != nil || (depth != 8 && depth != 10 && depth != 12) {
+		return fmt.Errorf("bit depth %q is not 08, 10 or 12", fields[2])
+	}
+
+	c.Profile = fmt.Sprintf("profile %d", profile)
+	c.Level = fmt.Sprintf("%d.%d", level/10, level%10)
+	c.Details = append(c.Details, fmt.Sprintf("%d-bit", depth))
+
+	return nil
+}
+
+// audioObjectTypes name the MPEG-4 audio object types.
+var audioObjectTypes = map[int]string{
+	1:  "AAC Main",
+	2:  "AAC-LC",
+	3:  "AAC SSR",
+	4:  "AAC LTP",
+	5:  "HE-AAC",
+	6:  "AAC Scalable",
+	29: "HE-AACv2",
+	34: "MP3",
+	42: "xHE-AAC",
+}
+
+// objectTypes name the audio of an mp4a codec by its object type
+// indication, other than 40 which is followed by an audio object type.
+var objectTypes = map[int]string{
+	0x66: "MPEG-2 AAC Main",
+	0x67: "MPEG-2 AAC-LC",
+	0x68: "MPEG-2 AAC SSR",
+	0x69: "MP3",
+	0x6b: "MP3",
+	0xa5: "Dolby Digital (AC-3)",
+	0xa6: "Dolby Digital Plus (E-AC-3)",
+	0xa9: "DTS",
+}
+
+// decodeMP4A decodes mp4a.OO[.A], the object type indication in hex and for
+// MPEG-4 audio the audio object type in decimal.
+func decodeMP4A(c *Codec, fields []string) error {
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Errorf("expected an object type and audio object type")
+	}
+
+	oti, err := strconv.ParseUint(fields[0], 16, 8)
+
+	if err != nil {
+		return fmt.Errorf("object type %q is not hex", fields[0])
+	}
+
+	if oti != 0x40 {
+		name, ok := objectTypes[int(oti)]
+
+		if !ok || len(fields) > 1 {
+			return fmt.Errorf("unknown object type %s", fields[0])
+		}
+
+		c.Name = name
+		return nil
+	}
+
+	if len(fields) != 2 {
+		return fmt.Errorf("MPEG-4 audio needs an audio object type")
+	}
+
+	aot, err := strconv.Atoi(fields[1])
+
+	if err != nil {
+		return fmt.Errorf("audio object type %q is not a number", fields[1])
+	}
+
+	name, ok := audioObjectTypes[aot]
+
+	if !ok {
+		return fmt.Errorf("unknown audio object type %d", aot)
+	}
+
+	c.Name = name
+
+	return nil
+}
+
+// decodeSTPP decodes stpp.ttml.PROFILE, the TTML profile of the subtitles.
+func decodeSTPP(c *Codec, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	if fields[0] != "ttml" || len(fields) != 2 {
+		return fmt.Errorf("expected ttml and a profile, e.g. stpp.ttml.im1t")
+	}
+
+	switch fields[1] {
+	case "im1t":
+		c.Profile = "IMSC1 text"
+	case "im1i":
+		c.Profile = "IMSC1 image"
+	case "im2t":
+		c.Profile = "IMSC2 text"
+	case "im2i":
+		c.Profile = "IMSC2 image"
+	default:
+		c.Profile = fields[1]
+	}
+
+	return nil
+}
+
+// hexBytes decodes a string of hex digits two to a byte.
+func hexBytes(s string) ([]byte, error) {
+	b := make([]byte, len(s)/2)
+
+	for i := range b {
+		n, err := strconv.ParseUint(s[2*i:2*i+2], 16, 8)
+
+		if err != nil {
+			return nil, fmt.Errorf("%q is not hex", s)
+		}
+
+		b[i] = byte(n)
+	}
+
+	return b, nil
+}
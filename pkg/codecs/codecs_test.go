@@ -0,0 +1,156 @@
+package codecs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		codec string
+		kind  string
+		want  string
+	}{
+		{"avc1.64001f", KindVideo, "H.264 High L3.1"},
+		{"avc1.640028", KindVideo, "H.264 High L4.0"},
+		{"avc1.42E01E", KindVideo, "H.264 Constrained Baseline L3.0"},
+		{"avc1.42001e", KindVideo, "H.264 Baseline L3.0"},
+		{"avc1.4d401f", KindVideo, "H.264 Main L3.1"},
+		{"avc1.42f00b", KindVideo, "H.264 Constrained Baseline L1b"},
+		{"avc1.640009", KindVideo, "H.264 High L1b"},
+		{"avc1.64000b", KindVideo, "H.264 High L1.1"},
+		{"avc3.6e0033", KindVideo, "H.264 High 10 L5.1"},
+		{"avc1.66.30", KindVideo, "H.264 Baseline L3.0"},
+		{"hvc1.2.4.L123.B0", KindVideo, "HEVC Main 10 L4.1"},
+		{"hvc1.1.6.L93.B0", KindVideo, "HEVC Main L3.1"},
+		{"hev1.1.6.H150.90", KindVideo, "HEVC Main High tier L5.0"},
+		{"hvc1.A4.10.L120", KindVideo, "HEVC Range Extensions L4.0"},
+		{"dvh1.05.06", KindVideo, "Dolby Vision profile 5 L6"},
+		{"av01.0.04M.10.0.112.09.16.09.0", KindVideo, "AV1 Main L3.0 10-bit"},
+		{"av01.0.13H.08", KindVideo, "AV1 Main High tier L5.1 8-bit"},
+		{"vp09.00.10.08", KindVideo, "VP9 profile 0 L1.0 8-bit"},
+		{"vp09.02.41.10.01.09.16.09.01", KindVideo, "VP9 profile 2 L4.1 10-bit"},
+		{"mp4a.40.2", KindAudio, "AAC-LC"},
+		{"mp4a.40.5", KindAudio, "HE-AAC"},
+		{"mp4a.40.29", KindAudio, "HE-AACv2"},
+		{"mp4a.40.34", KindAudio, "MP3"},
+		{"mp4a.40.42", KindAudio, "xHE-AAC"},
+		{"mp4a.67", KindAudio, "MPEG-2 AAC-LC"},
+		{"mp4a.6B", KindAudio, "MP3"},
+		{"ac-3", KindAudio, "Dolby Digital (AC-3)"},
+		{"ec-3", KindAudio, "Dolby Digital Plus (E-AC-3)"},
+		{"opus", KindAudio, "Opus"},
+		{"Opus", KindAudio, "Opus"},
+		{"fLaC", KindAudio, "FLAC"},
+		{"stpp.ttml.im1t", KindSubtitles, "TTML IMSC1 text"},
+		{"wvtt", KindSubtitles, "WebVTT"},
+		{" avc1.64001f ", KindVideo, "H.264 High L3.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.codec, func(t *testing.T) {
+			c, err := Parse(tt.codec)
+
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %s", tt.codec, err)
+			}
+
+			if got := c.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %q, want %q", tt.codec, got, tt.want)
+			}
+
+			if c.Kind != tt.kind {
+				t.Errorf("Parse(%q) kind = %q, want %q", tt.codec, c.Kind, tt.kind)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		codec string
+		err   string
+	}{
+		{"", "unknown codec"},
+		{"xyz1", "unknown codec"},
+		{"AVC1.64001f", "unknown codec"},
+		{"avc1", "expected six hex digits"},
+		{"avc1.64001", "expected six hex digits"},
+		{"avc1.zz001f", "is not hex"},
+		{"avc1.ff001f", "unknown profile 255"},
+		{"avc1.640070", "unknown level 112"},
+		{"avc1.640008", "unknown level 8"},
+		{"hvc1.2.4", "expected profile"},
+		{"hvc1.7.4.L123", "unknown profile 7"},
+		{"hvc1.2.xyz.L123", "compatibility flags"},
+		{"hvc1.2.4.X123", "should be L or H"},
+		{"hvc1.2.4.L124", "not a multiple of 3"},
+		{"hvc1.2.4.L123.B0.0.0.0.0.0.0", "more than six constraint bytes"},
+		{"dvh1.05", "expected profile and level"},
+		{"dvh1.05.14", "not from 1 to 13"},
+		{"av01.3.04M.10", "unknown profile"},
+		{"av01.0.04X.10", "should be two digits and M or H"},
+		{"av01.0.04M.09", "bit depth"},
+		{"vp09.04.10.08", "not from 00 to 03"},
+		{"vp09.00.70.08", "not from 10 to 62"},
+		{"mp4a", "expected an object type"},
+		{"mp4a.40", "needs an audio object type"},
+		{"mp4a.40.99", "unknown audio object type 99"},
+		{"mp4a.zz", "is not hex"},
+		{"mp4a.20", "unknown object type"},
+		{"stpp.xml.im1t", "expected ttml"},
+		{"ec-3.1", "takes no parameters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.codec, func(t *testing.T) {
+			_, err := Parse(tt.codec)
+
+			if err == nil {
+				t.Fatalf("Parse(%q) succeeded, want an error containing %q", tt.codec, tt.err)
+			}
+
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Parse(%q) error = %q, want it to contain %q", tt.codec, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		codecs string
+		want   string
+	}{
+		{"avc1.64001f,mp4a.40.2", "H.264 High L3.1, AAC-LC"},
+		{"hvc1.2.4.L153.B0, ec-3", "HEVC Main 10 L5.1, Dolby Digital Plus (E-AC-3)"},
+		{"avc1.42c0,mp4a.40.2", "avc1.42c0?, AAC-LC"},
+		{"", ""},
+		{"mp4a.40.2,,", "AAC-LC"},
+	}
+
+	for _, tt := range tests {
+		if got := Describe(tt.codecs); got != tt.want {
+			t.Errorf("Describe(%q) = %q, want %q", tt.codecs, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		codecs string
+		errs   int
+	}{
+		{"avc1.64001f,mp4a.40.2", 0},
+		{"avc1.64001f,zzz1", 1},
+		{"avc1.42c0,hvc1.2.4.L155.B0,mp4a.40.2", 2},
+		{"", 1},
+		{" ", 1},
+	}
+
+	for _, tt := range tests {
+		if errs := Validate(tt.codecs); len(errs) != tt.errs {
+			t.Errorf("Validate(%q) = %v, want %d errors", tt.codecs, errs, tt.errs)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package dashboard
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultInterval is used when the config does not set an interval.
+const defaultInterval = 5 * time.Second
+
+// Config is the list of streams to monitor.
+//
+// It is read from a small subset of YAML, a mapping with an interval and a
+// list of streams, each either a url or a mapping with a name, url and
+// variant:
+//
+//	interval: 5s
+//	streams:
+//	  - name: News
+//	    url: https://example.com/news/master.m3u8
+//	    variant: 2
+//	  - https://example.com/sport/master.m3u8
+type Config struct {
+	Interval time.Duration
+	Streams  []Stream
+}
+
+// Stream is a master or media playlist to monitor.
+type Stream struct {
+	Name string
+	URL  string
+
+	// Variant is the number of the variant to follow as shown in the variant
+	// list, starting from 1.
+	Variant int
+}
+
+// LoadConfig reads the config from file.
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := ParseConfig(string(data))
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return config, nil
+}
+
+// ParseConfig parses the config from YAML. Anything outside of the subset
+// described by Config is an error.
+func ParseConfig(data string) (*Config, error) {
+	config := &Config{Interval: defaultInterval}
+
+	inStreams := false
+	itemIndent := -1
+	var stream *Stream
+
+	// finish adds the stream that is being read to the list.
+	finish := func(line int) error {
+		if stream == nil {
+			return nil
+		}
+
+		if stream.URL == "" {
+			return fmt.Errorf("line %d: stream has no url", line)
+		}
+
+		if stream.Name == "" {
+			stream.Name = stream.URL
+		}
+
+		config.Streams = append(config.Streams, *stream)
+		stream = nil
+
+		return nil
+	}
+
+	lines := strings.Split(strings.Replace(data, "\r\n", "\n", -1), "\n")
+
+	for i, raw := range lines {
+		number := i + 1
+		line := stripComment(raw)
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "\t") {
+			return nil, fmt.Errorf("line %d: indent with spaces, not tabs", number)
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		line = strings.TrimSpace(line)
+
+		// Top level keys.
+		if indent == 0 {
+			if err := finish(number); err != nil {
+				return nil, err
+			}
+
+			key, value, err := splitKey(line)
+
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+
+			inStreams = false
+
+			switch key {
+			case "interval":
+				d, err := parseInterval(value)
+
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", number, err)
+				}
+
+				config.Interval = d
+			case "streams":
+				if value != "" {
+					return nil, fmt.Errorf("line %d: streams must be a list", number)
+				}
+
+				inStreams = true
+			default:
+				return nil, fmt.Errorf("line %d: unknown key %q", number, key)
+			}
+
+			continue
+		}
+
+		if !inStreams {
+			return nil, fmt.Errorf("line %d: unexpected indent", number)
+		}
+
+		// A new item in the list of streams.
+		if line == "-" || strings.HasPrefix(line, "- ") {
+			if err := finish(number); err != nil {
+				return nil, err
+			}
+
+			stream = &Stream{Variant: 1}
+			itemIndent = indent + 2
+			line = strings.TrimSpace(strings.TrimPrefix(line, "-"))
+
+			if line == "" {
+				continue
+			}
+
+			// A bare url rather than a mapping.
+			if !strings.Contains(line, ": ") && !strings.HasSuffix(line, ":") {
+				stream.URL = unquote(line)
+				continue
+			}
+		} else if stream == nil || indent != itemIndent {
+			return nil, fmt.Errorf("line %d: unexpected indent", number)
+		}
+
+		key, value, err := splitKey(line)
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+
+		switch key {
+		case "name":
+			stream.Name = value
+		case "url":
+			stream.URL = value
+		case "variant":
+			n, err := strconv.Atoi(value)
+
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("line %d: variant must be a number from 1", number)
+			}
+
+			stream.Variant = n
+		default:
+			return nil, fmt.Errorf("line %d: unknown stream key %q", number, key)
+		}
+	}
+
+	if err := finish(len(lines)); err != nil {
+		return nil, err
+	}
+
+	if len(config.Streams) == 0 {
+		return nil, fmt.Errorf("no streams")
+	}
+
+	return config, nil
+}
+
+// splitKey splits a "key: value" line, unquoting the value.
+func splitKey(line string) (string, string, error) {
+	i := strings.Index(line, ":")
+
+	if i < 1 || (i < len(line)-1 && line[i+1] != ' ') {
+		return "", "", fmt.Errorf("expected key: value, got %q", line)
+	}
+
+	return line[:i], unquote(strings.TrimSpace(line[i+1:])), nil
+}
+
+// stripComment removes a trailing comment, a # at the start of the line or
+// after a space that is not in quotes.
+func stripComment(line string) string {
+	var quote rune
+
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// unquote removes the quotes around a value.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
+
+// parseInterval reads a duration such as 5s, or a number of seconds.
+func parseInterval(value string) (time.Duration, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		value = fmt.Sprintf("%ds", n)
+	}
+
+	d, err := time.ParseDuration(value)
+
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("interval must be a duration such as 5s, got %q", value)
+	}
+
+	return d, nil
+}
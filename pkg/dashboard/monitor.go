@@ -0,0 +1,190 @@
+package dashboard
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// Stream states.
+const (
+	StateWaiting = "waiting"
+	StateOK      = "ok"
+	StateAlert   = "alert"
+	StateError   = "error"
+)
+
+// Status is what is known about a stream from its reloads.
+type Status struct {
+	Stream Stream
+
+	// Reloads counts every reload and Errors the ones that failed, Err is the
+	// error of the last reload if it failed.
+	Reloads int
+	Errors  int
+	Err     error
+
+	// Updated is the time of the last reload and Latency how long its request
+	// took.
+	Updated time.Time
+	Latency time.Duration
+
+	// Edge is when the newest segment ends by its program date time, zero
+	// when the playlist has none.
+	Edge time.Time
+
+	// Sequence is the media sequence of the newest segment, -1 until a
+	// playlist with segments has loaded. LastChange is when new segments were
+	// last seen.
+	Sequence   int
+	LastChange time.Time
+
+	// Alerts are the health rules broken by the last reload.
+	Alerts []hls.Violation
+}
+
+// State sums up the status as one of the stream states.
+func (s *Status) State() string {
+	switch {
+	case s.Err != nil:
+		return StateError
+	case s.Reloads == 0:
+		return StateWaiting
+	case len(s.Alerts) > 0:
+		return StateAlert
+	}
+
+	return StateOK
+}
+
+// Age is how long it has been since new segments were seen, at now.
+func (s *Status) Age(now time.Time) time.Duration {
+	if s.LastChange.IsZero() {
+		return 0
+	}
+
+	return now.Sub(s.LastChange)
+}
+
+// EdgeLatency returns how far the last reload was behind the live edge by
+// program date time, 0 when it is not known.
+func (s *Status) EdgeLatency() time.Duration {
+	if s.Edge.IsZero() {
+		return 0
+	}
+
+	return s.Updated.Sub(s.Edge)
+}
+
+// Monitor reloads a list of streams and keeps the status of each.
+type Monitor struct {
+	config   *Config
+	fetcher  hls.Fetcher
+	mu       sync.Mutex
+	statuses []*Status
+	health   []*hls.Health
+	changed  chan struct{}
+}
+
+// NewMonitor creates a Monitor for the streams of config, a nil fetcher uses
+// hls.DefaultFetcher.
+func NewMonitor(config *Config, fetcher hls.Fetcher) *Monitor {
+	m := &Monitor{
+		config:  config,
+		fetcher: fetcher,
+		changed: make(chan struct{}, 1),
+	}
+
+	for _, stream := range config.Streams {
+		m.statuses = append(m.statuses, &Status{Stream: stream, Sequence: -1})
+		m.health = append(m.health, hls.NewHealth())
+	}
+
+	return m
+}
+
+// Run reloads every stream at the config interval until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for i, stream := range m.config.Streams {
+		updates := hls.Watch(ctx, stream.URL, hls.WatchOptions{
+			Interval: m.config.Interval,
+			Variant:  stream.Variant - 1,
+			Fetcher:  m.fetcher,
+		})
+
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for u := range updates {
+				m.update(i, u)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// Changed receives a value after a stream has been reloaded.
+func (m *Monitor) Changed() <-chan struct{} {
+	return m.changed
+}
+
+// Statuses returns a copy of the status of every stream, in the order of the
+// config.
+func (m *Monitor) Statuses() []*Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]*Status, len(m.statuses))
+
+	for i, s := range m.statuses {
+		status := *s
+		statuses[i] = &status
+	}
+
+	return statuses
+}
+
+// update records a reload of the stream at index i.
+func (m *Monitor) update(i int, u *hls.Update) {
+	m.mu.Lock()
+
+	s := m.statuses[i]
+	s.Reloads++
+	s.Updated = u.Time
+	s.Err = u.Err
+	s.Latency = 0
+
+	if u.Response != nil {
+		s.Latency = u.Response.Latency
+	}
+
+	if u.Err != nil {
+		s.Errors++
+	}
+
+	s.Alerts = m.health[i].Check(u)
+	s.LastChange = m.health[i].LastChange()
+
+	if u.Err == nil {
+		s.Sequence = -1
+		s.Edge = u.Playlist.EdgeTime()
+
+		if last := u.Playlist.LastSegment(); last != nil {
+			s.Sequence = last.Sequence
+		}
+	}
+
+	m.mu.Unlock()
+
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
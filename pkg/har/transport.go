@@ -0,0 +1,383 @@
+package har
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configure a Transport.
+type Options struct {
+	// Creator is the version of hlstail written to the archive.
+	Creator Creator
+
+	// Bodies includes the text of playlist responses in the archive.
+	Bodies bool
+}
+
+// Transport is an http.RoundTripper that records every request made through
+// it in an HTTP Archive.
+type Transport struct {
+	base    http.RoundTripper
+	opts    Options
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+// NewTransport records the requests made with base, http.DefaultTransport is
+// used when it is nil.
+func NewTransport(base http.RoundTripper, opts Options) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		base: base,
+		opts: opts,
+	}
+}
+
+// timeline is when each phase of a request happened.
+type timeline struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wrote        time.Time
+	firstByte    time.Time
+	done         time.Time
+	remote       string
+}
+
+// RoundTrip makes the request and adds it to the archive once the body has
+// been read.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tl := &timeline{start: time.Now()}
+	var mu sync.Mutex
+
+	// The trace callbacks may run on other goroutines.
+	mark := func(at *time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if at.IsZero() {
+			*at = time.Now()
+		}
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { mark(&tl.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { mark(&tl.dnsDone) },
+		ConnectStart:      func(string, string) { mark(&tl.connectStart) },
+		ConnectDone:       func(string, string, error) { mark(&tl.connectDone) },
+		TLSHandshakeStart: func() { mark(&tl.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { mark(&tl.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			mark(&tl.gotConn)
+
+			mu.Lock()
+			tl.remote = info.Conn.RemoteAddr().String()
+			mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { mark(&tl.wrote) },
+		GotFirstResponseByte: func() { mark(&tl.firstByte) },
+	}
+
+	resp, err := t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+
+	if err != nil {
+		mu.Lock()
+		tl.done = time.Now()
+		entry := t.entry(req, nil, tl, nil, 0)
+		mu.Unlock()
+
+		entry.Error = err.Error()
+		t.add(entry)
+
+		return nil, err
+	}
+
+	capture := t.opts.Bodies && isPlaylist(req, resp)
+
+	resp.Body = &body{
+		ReadCloser: resp.Body,
+		capture:    capture,
+		done: func(text []byte, size int64) {
+			mu.Lock()
+			mark := *tl
+			mark.done = time.Now()
+			mu.Unlock()
+
+			t.add(t.entry(req, resp, &mark, text, size))
+		},
+	}
+
+	return resp, nil
+}
+
+// add appends an entry to the archive.
+func (t *Transport) add(e *Entry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entries = append(t.entries, e)
+}
+
+// HAR returns the archive of the requests completed so far, in the order they
+// were started.
+func (t *Transport) HAR() *HAR {
+	t.mu.Lock()
+	entries := append([]*Entry{}, t.entries...)
+	t.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime < entries[j].StartedDateTime
+	})
+
+	return &HAR{
+		Log: Log{
+			Version: "1.2",
+			Creator: t.opts.Creator,
+			Entries: entries,
+		},
+	}
+}
+
+// WriteFile writes the archive to file.
+func (t *Transport) WriteFile(file string) error {
+	data, err := json.MarshalIndent(t.HAR(), "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+// entry builds the archive entry of a request.
+func (t *Transport) entry(req *http.Request, resp *http.Response, tl *timeline, text []byte, size int64) *Entry {
+	e := &Entry{
+		StartedDateTime: tl.start.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Request: Request{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []Cookie{},
+			Headers:     headers(req.Header),
+			QueryString: []Header{},
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+		Response: Response{
+			Cookies:     []Cookie{},
+			Headers:     []Header{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		ServerIPAddress: hostOf(tl.remote),
+	}
+
+	if e.Request.HTTPVersion == "" {
+		e.Request.HTTPVersion = "HTTP/1.1"
+	}
+
+	if req.Host != "" {
+		e.Request.Headers = append([]Header{{Name: "Host", Value: req.Host}}, e.Request.Headers...)
+	}
+
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			e.Request.QueryString = append(e.Request.QueryString, Header{Name: name, Value: value})
+		}
+	}
+
+	if resp != nil {
+		e.Response.Status = resp.StatusCode
+		e.Response.StatusText = http.StatusText(resp.StatusCode)
+		e.Response.HTTPVersion = resp.Proto
+		e.Response.Headers = headers(resp.Header)
+		e.Response.RedirectURL = resp.Header.Get("Location")
+		e.Response.BodySize = size
+		e.Response.Content = Content{
+			Size:     size,
+			MimeType: resp.Header.Get("Content-Type"),
+			Text:     string(text),
+		}
+	}
+
+	e.Timings = timings(tl)
+
+	for _, phase := range []float64{e.Timings.Blocked, e.Timings.DNS, e.Timings.Connect, e.Timings.Send, e.Timings.Wait, e.Timings.Receive} {
+		if phase > 0 {
+			e.Time += phase
+		}
+	}
+
+	return e
+}
+
+// timings works out the phases of a request. Connect includes the TLS
+// handshake as the format requires.
+func timings(tl *timeline) Timings {
+	t := Timings{Blocked: -1, DNS: -1, Connect: -1, Send: 0, Wait: 0, Receive: 0, SSL: -1}
+
+	// Anything before the connection was looked up or reused is time spent
+	// waiting for one.
+	first := firstOf(tl.dnsStart, tl.connectStart, tl.gotConn)
+
+	if !first.IsZero() {
+		t.Blocked = ms(first.Sub(tl.start))
+	}
+
+	if !tl.dnsStart.IsZero() && !tl.dnsDone.IsZero() {
+		t.DNS = ms(tl.dnsDone.Sub(tl.dnsStart))
+	}
+
+	if !tl.connectStart.IsZero() {
+		end := tl.connectDone
+
+		if tl.tlsDone.After(end) {
+			end = tl.tlsDone
+		}
+
+		if !end.IsZero() {
+			t.Connect = ms(end.Sub(tl.connectStart))
+		}
+	}
+
+	if !tl.tlsStart.IsZero() && !tl.tlsDone.IsZero() {
+		t.SSL = ms(tl.tlsDone.Sub(tl.tlsStart))
+	}
+
+	if !tl.gotConn.IsZero() && !tl.wrote.IsZero() {
+		t.Send = ms(tl.wrote.Sub(tl.gotConn))
+	}
+
+	if !tl.wrote.IsZero() && !tl.firstByte.IsZero() {
+		t.Wait = ms(tl.firstByte.Sub(tl.wrote))
+	}
+
+	if !tl.firstByte.IsZero() && !tl.done.IsZero() {
+		t.Receive = ms(tl.done.Sub(tl.firstByte))
+	}
+
+	return t
+}
+
+// body reports the size, and optionally the contents, of a response body
+// once it has been read to the end or closed.
+type body struct {
+	io.ReadCloser
+	capture bool
+	buffer  bytes.Buffer
+	size    int64
+	done    func(text []byte, size int64)
+	once    sync.Once
+}
+
+// Read reads from the response body.
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+
+	if b.capture {
+		b.buffer.Write(p[:n])
+	}
+
+	if err == io.EOF {
+		b.finish()
+	}
+
+	return n, err
+}
+
+// Close closes the response body.
+func (b *body) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+
+	return err
+}
+
+// finish records the entry the first time the body is done with.
+func (b *body) finish() {
+	b.once.Do(func() {
+		var text []byte
+
+		if b.capture {
+			text = b.buffer.Bytes()
+		}
+
+		b.done(text, b.size)
+	})
+}
+
+// isPlaylist reports whether the response is a playlist.
+func isPlaylist(req *http.Request, resp *http.Response) bool {
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+
+	return strings.Contains(contentType, "mpegurl") || strings.HasSuffix(req.URL.Path, ".m3u8")
+}
+
+// headers converts headers to archive form, sorted by name.
+func headers(h http.Header) []Header {
+	list := []Header{}
+
+	for name, values := range h {
+		for _, value := range values {
+			list = append(list, Header{Name: name, Value: value})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// firstOf returns the earliest of the times that are set.
+func firstOf(times ...time.Time) time.Time {
+	var first time.Time
+
+	for _, t := range times {
+		if !t.IsZero() && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+	}
+
+	return first
+}
+
+// hostOf returns the ip address of a host:port address.
+func hostOf(addr string) string {
+	if i := strings.LastIndex(addr, ":"); i >= 0 {
+		return strings.Trim(addr[:i], "[]")
+	}
+
+	return addr
+}
+
+// ms converts d to fractional milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,287 @@
+package hls
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pdtTolerance is how far apart the program date times of the same segment in
+// two playlists can be before they are considered different.
+const pdtTolerance = 500 * time.Millisecond
+
+// adTags are the tags that mark ad breaks.
+var adTags = []string{
+	"EXT-X-CUE-OUT",
+	"EXT-X-CUE-OUT-CONT",
+	"EXT-X-CUE-IN",
+	"EXT-X-CUE",
+	"EXT-X-DATERANGE",
+	"EXT-X-SCTE35",
+	"EXT-OATCLS-SCTE35",
+	"EXT-X-SPLICEPOINT-SCTE35",
+}
+
+// SegmentMatch is the segment with the same media sequence in two playlists.
+// A or B is nil when only one of the playlists has it.
+type SegmentMatch struct {
+	Sequence int
+	A        *Segment
+	B        *Segment
+
+	// Differences describe how the segments differ, empty when they match.
+	Differences []string
+}
+
+// Comparison is the result of comparing two media playlists that should
+// carry the same stream, such as a primary and backup or two CDNs.
+type Comparison struct {
+	A *MediaPlaylist
+	B *MediaPlaylist
+
+	// Segments has an entry for every media sequence in either playlist, in
+	// order.
+	Segments []*SegmentMatch
+
+	// Lead is how many segments, and LeadTime how much media, the live edge
+	// of A is ahead of B. Negative when B leads.
+	Lead     int
+	LeadTime time.Duration
+
+	// Offset is how much later the program date time of B is than A for the
+	// newest segment they share, HasOffset is false when it is unknown.
+	Offset    time.Duration
+	HasOffset bool
+
+	// SequenceOffset is how many media sequences later B has the segment with
+	// the program date time of the newest segment of A.
+	SequenceOffset int
+
+	// Problems are the differences that would make a player jump, stall or
+	// lose an ad break when failing over from one playlist to the other.
+	Problems []string
+}
+
+// Aligned reports whether a player could switch between the playlists without
+// a jump.
+func (c *Comparison) Aligned() bool {
+	return len(c.Problems) == 0
+}
+
+// Compare matches the segments of a and b by media sequence.
+func Compare(a *MediaPlaylist, b *MediaPlaylist) *Comparison {
+	c := &Comparison{A: a, B: b}
+
+	bySequence := make(map[int]*SegmentMatch)
+
+	for _, s := range a.Segments {
+		match := &SegmentMatch{Sequence: s.Sequence, A: s}
+		bySequence[s.Sequence] = match
+		c.Segments = append(c.Segments, match)
+	}
+
+	for _, s := range b.Segments {
+		if match, ok := bySequence[s.Sequence]; ok {
+			match.B = s
+			continue
+		}
+
+		match := &SegmentMatch{Sequence: s.Sequence, B: s}
+		bySequence[s.Sequence] = match
+		c.Segments = append(c.Segments, match)
+	}
+
+	sort.Slice(c.Segments, func(i, j int) bool {
+		return c.Segments[i].Sequence < c.Segments[j].Sequence
+	})
+
+	numbersA, numbersB := discontinuityNumbers(a), discontinuityNumbers(b)
+
+	shared, uris, discontinuities, numbers, ads, dates := 0, 0, 0, 0, 0, 0
+
+	for _, m := range c.Segments {
+		if m.A == nil || m.B == nil {
+			continue
+		}
+
+		shared++
+
+		if segmentName(a, m.A) != segmentName(b, m.B) {
+			m.Differences = append(m.Differences, fmt.Sprintf("uri %s / %s", segmentName(a, m.A), segmentName(b, m.B)))
+			uris++
+		}
+
+		if !m.A.ProgramDateTime.IsZero() && !m.B.ProgramDateTime.IsZero() {
+			offset := m.B.ProgramDateTime.Sub(m.A.ProgramDateTime)
+
+			if offset > pdtTolerance || offset < -pdtTolerance {
+				m.Differences = append(m.Differences, fmt.Sprintf("pdt %+.3fs", offset.Seconds()))
+				dates++
+			}
+
+			c.Offset = offset
+			c.HasOffset = true
+		}
+
+		if m.A.Discontinuity != m.B.Discontinuity {
+			m.Differences = append(m.Differences, "discontinuity in one only")
+			discontinuities++
+		} else if numbersA[m.Sequence] != numbersB[m.Sequence] {
+			m.Differences = append(m.Differences, fmt.Sprintf("discontinuity sequence %d / %d", numbersA[m.Sequence], numbersB[m.Sequence]))
+			numbers++
+		}
+
+		if adA, adB := adMarkers(m.A), adMarkers(m.B); adA != adB {
+			m.Differences = append(m.Differences, fmt.Sprintf("ad markers %s / %s", orNone(adA), orNone(adB)))
+			ads++
+		}
+	}
+
+	lastA, lastB := a.LastSegment(), b.LastSegment()
+
+	if lastA != nil && lastB != nil {
+		c.Lead = lastA.Sequence - lastB.Sequence
+		c.LeadTime = mediaBetween(a, b, lastA, lastB)
+	}
+
+	if lastA != nil && !lastA.ProgramDateTime.IsZero() {
+		for _, s := range b.Segments {
+			if d := s.ProgramDateTime.Sub(lastA.ProgramDateTime); !s.ProgramDateTime.IsZero() && d <= pdtTolerance && d >= -pdtTolerance {
+				c.SequenceOffset = s.Sequence - lastA.Sequence
+			}
+		}
+	}
+
+	if c.SequenceOffset != 0 {
+		c.Problems = append(c.Problems, fmt.Sprintf("the same program date time is %+d media sequences apart, failover would jump", c.SequenceOffset))
+	}
+
+	if shared == 0 && len(a.Segments) > 0 && len(b.Segments) > 0 {
+		c.Problems = append(c.Problems, "no media sequence in common, failover would jump")
+	}
+
+	if c.HasOffset && (c.Offset > pdtTolerance || c.Offset < -pdtTolerance) {
+		c.Problems = append(c.Problems, fmt.Sprintf("the same media sequence is %.3fs apart by program date time, failover would jump", c.Offset.Seconds()))
+	} else if dates > 0 {
+		c.Problems = append(c.Problems, fmt.Sprintf("%s program date times more than %s apart", segments(dates, "has", "have"), pdtTolerance))
+	}
+
+	if numbers > 0 {
+		c.Problems = append(c.Problems, fmt.Sprintf("%s a different discontinuity sequence", segments(numbers, "has", "have")))
+	}
+
+	if discontinuities > 0 {
+		c.Problems = append(c.Problems, fmt.Sprintf("%s a discontinuity in one playlist only", segments(discontinuities, "has", "have")))
+	}
+
+	if ads > 0 {
+		c.Problems = append(c.Problems, fmt.Sprintf("%s different ad markers", segments(ads, "has", "have")))
+	}
+
+	if uris > 0 {
+		c.Problems = append(c.Problems, fmt.Sprintf("%s different uris", segments(uris, "has", "have")))
+	}
+
+	return c
+}
+
+// mediaBetween returns how much media the live edge of a is ahead of b. The
+// program date times are used when both have them, otherwise the durations of
+// the segments only one of them has.
+func mediaBetween(a *MediaPlaylist, b *MediaPlaylist, lastA *Segment, lastB *Segment) time.Duration {
+	if !lastA.ProgramDateTime.IsZero() && !lastB.ProgramDateTime.IsZero() {
+		return segmentEnd(lastA).Sub(segmentEnd(lastB))
+	}
+
+	ahead, behind := a, lastB.Sequence
+
+	if lastB.Sequence > lastA.Sequence {
+		ahead, behind = b, lastA.Sequence
+	}
+
+	var d time.Duration
+
+	for _, s := range ahead.Segments {
+		if s.Sequence > behind {
+			d += time.Duration(s.Duration * float64(time.Second))
+		}
+	}
+
+	if ahead == b {
+		return -d
+	}
+
+	return d
+}
+
+// segmentEnd returns the program date time at the end of the segment.
+func segmentEnd(s *Segment) time.Time {
+	return s.ProgramDateTime.Add(time.Duration(s.Duration * float64(time.Second)))
+}
+
+// segmentName returns the file name of a segment so that the same segment
+// matches when it is served from different hosts or paths.
+func segmentName(p *MediaPlaylist, s *Segment) string {
+	u, err := url.Parse(p.ResolveURI(s.URI))
+
+	if err != nil {
+		return s.URI
+	}
+
+	return path.Base(u.Path)
+}
+
+// adMarkers returns the ad break tags of a segment.
+func adMarkers(s *Segment) string {
+	var markers []string
+
+	for _, tag := range s.Tags {
+		name, _ := splitTag(tag)
+
+		for _, ad := range adTags {
+			if name == ad {
+				markers = append(markers, tag)
+			}
+		}
+	}
+
+	return strings.Join(markers, " ")
+}
+
+// segments counts n segments followed by the singular or plural verb.
+func segments(n int, singular string, plural string) string {
+	if n == 1 {
+		return "1 segment " + singular
+	}
+
+	return fmt.Sprintf("%d segments %s", n, plural)
+}
+
+// orNone returns "none" for an empty string.
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+
+	return s
+}
+
+// discontinuityNumbers returns the discontinuity sequence number of each
+// segment by media sequence.
+func discontinuityNumbers(p *MediaPlaylist) map[int]int {
+	numbers := make(map[int]int)
+	number := p.DiscontinuitySequence
+
+	for _, s := range p.Segments {
+		if s.Discontinuity {
+			number++
+		}
+
+		numbers[s.Sequence] = number
+	}
+
+	return numbers
+}
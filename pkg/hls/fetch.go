@@ -0,0 +1,97 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Response is a fetched playlist body along with its HTTP metadata.
+type Response struct {
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Fetched    time.Time
+	Latency    time.Duration
+}
+
+// Fetcher retrieves playlists. A response is returned with the error for non
+// 2xx statuses so the metadata is still available.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (*Response, error)
+}
+
+// DefaultFetcher is used by anything that was not given a Fetcher. It reads
+// urls over HTTP, local files and standard input.
+var DefaultFetcher = NewFetcher(http.DefaultClient)
+
+// stdinFetcher is shared so standard input is only ever read once.
+var stdinFetcher = NewReaderFetcher(os.Stdin)
+
+// NewFetcher returns a Fetcher that makes HTTP requests with client and also
+// reads local files and standard input.
+func NewFetcher(client *http.Client) Fetcher {
+	return &SourceFetcher{
+		HTTP:  &HTTPFetcher{Client: client},
+		File:  NewFileFetcher(),
+		Stdin: stdinFetcher,
+	}
+}
+
+// HTTPFetcher fetches playlists over HTTP.
+type HTTPFetcher struct {
+	Client *http.Client
+}
+
+// Fetch requests url and reads the whole body.
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	data, err := f.Client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer data.Body.Close()
+
+	body, err := ioutil.ReadAll(data.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &Response{
+		URL:        url,
+		StatusCode: data.StatusCode,
+		Header:     data.Header,
+		Body:       body,
+		Fetched:    start,
+		Latency:    time.Since(start),
+	}
+
+	if data.StatusCode < 200 || data.StatusCode > 299 {
+		return resp, fmt.Errorf("unexpected status %s", data.Status)
+	}
+
+	return resp, nil
+}
+
+// orDefault returns the fetcher, or DefaultFetcher when it is nil.
+func orDefault(f Fetcher) Fetcher {
+	if f == nil {
+		return DefaultFetcher
+	}
+
+	return f
+}
@@ -0,0 +1,103 @@
+package hls
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Health rule names.
+const (
+	RuleStale           = "stale"
+	RuleSegmentDuration = "segment_duration"
+	RuleMediaSequence   = "media_sequence"
+	RuleEmptyPlaylist   = "empty_playlist"
+)
+
+// staleTargetDurations is how many target durations a live playlist can go
+// without a new segment before it is considered stale.
+const staleTargetDurations = 3
+
+// defaultTargetDuration is assumed when a playlist has no target duration.
+const defaultTargetDuration = 10
+
+// Violation is a health rule broken by a reload.
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// Health checks the reloads of a single variant against a set of rules. It
+// needs to see every reload to know how long the playlist has been stale.
+type Health struct {
+	lastChange time.Time
+}
+
+// NewHealth creates a Health with no history.
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// LastChange returns the time new segments were last seen.
+func (h *Health) LastChange() time.Time {
+	return h.lastChange
+}
+
+// Check returns the rules broken by the update. Failed reloads are not
+// checked, the error says enough.
+func (h *Health) Check(u *Update) []Violation {
+	violations := make([]Violation, 0)
+
+	if u.Err != nil {
+		return violations
+	}
+
+	p := u.Playlist
+
+	if len(u.Added) > 0 || h.lastChange.IsZero() {
+		h.lastChange = u.Time
+	}
+
+	if len(p.Segments) == 0 {
+		violations = append(violations, Violation{
+			Rule:    RuleEmptyPlaylist,
+			Message: "playlist has no segments",
+		})
+	}
+
+	target := p.TargetDuration
+
+	if target <= 0 {
+		target = defaultTargetDuration
+	}
+
+	// A live playlist should grow roughly once per target duration.
+	if !p.EndList {
+		stale := u.Time.Sub(h.lastChange)
+
+		if stale > time.Duration(staleTargetDurations*target)*time.Second {
+			violations = append(violations, Violation{
+				Rule:    RuleStale,
+				Message: fmt.Sprintf("no new segments for %s", stale.Round(time.Second)),
+			})
+		}
+	}
+
+	for _, s := range u.Added {
+		if p.TargetDuration > 0 && int(math.Round(s.Duration)) > p.TargetDuration {
+			violations = append(violations, Violation{
+				Rule:    RuleSegmentDuration,
+				Message: fmt.Sprintf("segment %d is %.3fs, longer than the target duration of %ds", s.Sequence, s.Duration, p.TargetDuration),
+			})
+		}
+	}
+
+	if u.Previous != nil && p.MediaSequence < u.Previous.MediaSequence {
+		violations = append(violations, Violation{
+			Rule:    RuleMediaSequence,
+			Message: fmt.Sprintf("media sequence went back from %d to %d", u.Previous.MediaSequence, p.MediaSequence),
+		})
+	}
+
+	return violations
+}
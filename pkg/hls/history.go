@@ -0,0 +1,82 @@
+package hls
+
+import (
+	"strconv"
+	"strings"
+)
+
+// History keeps the segments seen across the reloads of a variant, dropping
+// the oldest once it holds max of them.
+type History struct {
+	max      int
+	segments []*Segment
+}
+
+// NewHistory creates a History holding up to max segments.
+func NewHistory(max int) *History {
+	return &History{max: max}
+}
+
+// Add records the segments added by an update.
+func (h *History) Add(u *Update) {
+	if u.Err != nil {
+		return
+	}
+
+	h.segments = append(h.segments, u.Added...)
+
+	if len(h.segments) > h.max {
+		// Copy so the dropped segments can be collected.
+		h.segments = append([]*Segment{}, h.segments[len(h.segments)-h.max:]...)
+	}
+}
+
+// Len returns the number of segments in the history.
+func (h *History) Len() int {
+	return len(h.segments)
+}
+
+// Segments returns the segments in the history, oldest first.
+func (h *History) Segments() []*Segment {
+	return h.segments
+}
+
+// Index returns the position of the segment in the history, or -1 if it is
+// not there.
+func (h *History) Index(segment *Segment) int {
+	for i, s := range h.segments {
+		if s == segment {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Search returns the position of the newest segment before the position
+// before that matches query, or -1 if none do. A query matches the media
+// sequence of a segment when it is a number, or any part of its uri or tags.
+func (h *History) Search(query string, before int) int {
+	if before > len(h.segments) {
+		before = len(h.segments)
+	}
+
+	sequence, err := strconv.Atoi(query)
+	isSequence := err == nil
+
+	for i := before - 1; i >= 0; i-- {
+		s := h.segments[i]
+
+		if isSequence && s.Sequence == sequence {
+			return i
+		}
+
+		for _, line := range s.Lines() {
+			if strings.Contains(line, query) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
@@ -1,13 +1,9 @@
 package hls
 
 import (
-	"bytes"
+	"context"
 	"errors"
-	"fmt"
-	"io/ioutil"
-	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 )
 
@@ -15,47 +11,74 @@ import (
 type Master struct {
 	url      string
 	rawData  string
+	Fetcher  Fetcher
 	Variants []*Variant
 }
 
-// NewMaster creates a new Master
+// NewMaster creates a new Master, url may also be a local path or StdinURL.
 func NewMaster(url string) *Master {
 	return &Master{
-		url: url,
+		url: Location(url),
 	}
 }
 
 // Get loads the data into memory to be used later.
 func (m *Master) Get() error {
-	data, err := http.Get(m.url)
+	return m.get(context.Background())
+}
+
+func (m *Master) get(ctx context.Context) error {
+	resp, err := orDefault(m.Fetcher).Fetch(ctx, m.url)
 
 	if err != nil {
 		return err
 	}
 
-	defer data.Body.Close()
+	m.rawData = string(resp.Body)
 
-	body, err := ioutil.ReadAll(data.Body)
+	return m.parse()
+}
 
-	if err != nil {
-		return err
+// ParseMaster parses the variants of a master playlist fetched from url. A
+// media playlist is treated as the only variant.
+func ParseMaster(url string, rawData string) (*Master, error) {
+	m := &Master{
+		url:     Location(url),
+		rawData: rawData,
 	}
 
-	m.rawData = string(body)
+	if err := m.parse(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// parse creates the variants from the raw playlist.
+func (m *Master) parse() error {
+	// A media playlist can be tailed directly, treat it as the only variant.
+	if !IsMasterPlaylist(m.rawData) {
+		m.Variants = []*Variant{{URL: m.url, Fetcher: m.Fetcher}}
+		return nil
+	}
 
-	rootURL, err := url.Parse(m.url)
+	rootURL, err := baseURL(m.url)
 	if err != nil {
 		return err
 	}
 
 	m.Variants = parseVariants(rootURL, m.rawData)
 
+	for _, variant := range m.Variants {
+		variant.Fetcher = m.Fetcher
+	}
+
 	return nil
 }
 
 // GetVariant returns a Variant struct representing the variant's data.
 func (m *Master) GetVariant(index int) (*Variant, error) {
-	if index > len(m.Variants) || index < 0 {
+	if index >= len(m.Variants) || index < 0 {
 		return nil, errors.New("index out of range")
 	}
 
@@ -64,28 +87,6 @@ func (m *Master) GetVariant(index int) (*Variant, error) {
 	return variant, nil
 }
 
-// GetVariantList gets a printable list of variants
-func (m *Master) GetVariantList(selectedIndex int) string {
-	output := new(bytes.Buffer)
-
-	for i, variant := range m.Variants {
-
-		res := variant.Resolution
-
-		if res == "" {
-			res = "audio-only"
-		}
-
-		if i == selectedIndex {
-			fmt.Fprintf(output, "\033[0;30;47m%d) %s - %s -> %s\033[0m\r\n", i+1, res, strconv.Itoa(int(variant.Bandwidth)), variant.URL)
-		} else {
-			fmt.Fprintf(output, "%d) %s - %s -> %s\r\n", i+1, res, strconv.Itoa(int(variant.Bandwidth)), variant.URL)
-		}
-	}
-
-	return output.String()
-}
-
 func parseVariants(rootURL *url.URL, rawData string) []*Variant {
 	// Make a slice to store the variants to be printed.
 	variants := make([]*Variant, 0)
@@ -96,7 +97,7 @@ func parseVariants(rootURL *url.URL, rawData string) []*Variant {
 
 	// Loop over the lines and create variants.
 	for i := 0; i < len(lines); i++ {
-		line := lines[i]
+		line := strings.TrimSpace(lines[i])
 
 		if line == "" {
 			continue
@@ -106,65 +107,24 @@ func parseVariants(rootURL *url.URL, rawData string) []*Variant {
 			variant.Tags = append(variant.Tags, line)
 
 			// If this is a media tag then we need to parse it now rather than waiting for the source line.
-			if strings.Index(line, "#EXT-X-MEDIA") == 0 {
-
-				// Get the portion after the media tag
-				data := strings.Split(line, ":")
-
-				// If we don't have something we can parse, just continue
-				if len(data) != 2 {
-					continue
-				}
-
-				// Break out the key / value pairs
-				parts := strings.Split(data[1], ",")
-
-				for _, part := range parts {
-
-					kv := strings.Split(part, "=")
-
-					switch kv[0] {
-					case "URI":
+			if strings.Index(line, "#EXT-X-MEDIA:") == 0 {
+				_, value := splitTag(line)
 
-						variant.URL = strings.ReplaceAll(kv[1], "\"", "")
+				variant.Attributes = ParseAttributes(value)
+				variant.Type = variant.Attributes["TYPE"]
+				variant.Resolution = variant.Attributes["NAME"]
 
-						if strings.Index(variant.URL, "http") == -1 {
-							variant.URL = fmt.Sprintf("%s/%s", rootURL, variant.URL)
-						}
-					case "NAME":
-						variant.Resolution = kv[1]
-					default:
-						// Ignore any fields we don't about for now.
-						break
-					}
+				// Renditions without a uri are carried in the variant streams.
+				if uri, ok := variant.Attributes["URI"]; ok {
+					variant.URL = resolveURL(rootURL, uri)
+					variants = append(variants, variant)
 				}
 
-				variants = append(variants, variant)
-
 				// Create a new variant.
 				variant = &Variant{}
-
 			}
 		} else {
-
-			// Parse the url, and then apply the protocol and host from the parent.
-			u, err := url.Parse(line)
-			if err != nil {
-				variant.URL = "invalid-url"
-			}
-
-			// Here we want to fill in the blanks if the provided url is relative.
-			if u.Host == "" {
-				u.Host = rootURL.Host
-				rootPath := strings.Split(rootURL.Path, "/")
-				u.Path = fmt.Sprintf("%s/%s", strings.Join(rootPath[:len(rootPath)-1], "/"), u.Path)
-			}
-
-			if u.Scheme == "" {
-				u.Scheme = rootURL.Scheme
-			}
-
-			variant.URL = u.String()
+			variant.URL = resolveURL(rootURL, line)
 
 			variant.Process()
 
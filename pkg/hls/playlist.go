@@ -0,0 +1,355 @@
+package hls
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// playlistTags are the tags that describe the playlist as a whole rather than
+// the segment that follows them.
+var playlistTags = []string{
+	"EXTM3U",
+	"EXT-X-VERSION",
+	"EXT-X-TARGETDURATION",
+	"EXT-X-MEDIA-SEQUENCE",
+	"EXT-X-DISCONTINUITY-SEQUENCE",
+	"EXT-X-ENDLIST",
+	"EXT-X-PLAYLIST-TYPE",
+	"EXT-X-I-FRAMES-ONLY",
+	"EXT-X-INDEPENDENT-SEGMENTS",
+	"EXT-X-START",
+	"EXT-X-ALLOW-CACHE",
+	"EXT-X-SERVER-CONTROL",
+	"EXT-X-PART-INF",
+	"EXT-X-SKIP",
+	"EXT-X-PRELOAD-HINT",
+	"EXT-X-RENDITION-REPORT",
+}
+
+// ByteRange is the part of a resource that holds a segment.
+type ByteRange struct {
+	Length int64
+	Offset int64
+}
+
+// Map is the media initialization section of a segment.
+type Map struct {
+	URI       string
+	ByteRange *ByteRange
+}
+
+// Segment is a single media segment and the tags that precede it. Map and Key
+// hold the EXT-X-MAP and EXT-X-KEY in effect for the segment, which are not
+// necessarily among its own tags.
+type Segment struct {
+	Sequence        int
+	Duration        float64
+	Title           string
+	URI             string
+	ProgramDateTime time.Time
+	Discontinuity   bool
+	Gap             bool
+	ByteRange       *ByteRange
+	Map             *Map
+	Key             string
+	Tags            []string
+}
+
+// Lines returns the segment as it appeared in the playlist.
+func (s *Segment) Lines() []string {
+	return append(append([]string{}, s.Tags...), s.URI)
+}
+
+// MediaPlaylist is a parsed media playlist.
+type MediaPlaylist struct {
+	URL                   string
+	Raw                   string
+	Header                []string
+	Version               int
+	TargetDuration        int
+	MediaSequence         int
+	DiscontinuitySequence int
+	PlaylistType          string
+	EndList               bool
+	Segments              []*Segment
+}
+
+// ParseMediaPlaylist parses the body of a media playlist fetched from url.
+func ParseMediaPlaylist(url string, rawData string) *MediaPlaylist {
+	p := &MediaPlaylist{
+		URL: url,
+		Raw: rawData,
+	}
+
+	lines := strings.Split(rawData, "\n")
+
+	segment := &Segment{}
+
+	var pdt time.Time
+	var key string
+	var initMap *Map
+
+	// Byte ranges without an offset follow on from the previous range of the
+	// same resource.
+	rangeEnds := make(map[string]int64)
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			continue
+		}
+
+		if strings.Index(line, "#") != 0 {
+			// We've hit the uri line so the segment is complete.
+			segment.URI = line
+			segment.Sequence = p.MediaSequence + len(p.Segments)
+			segment.Key = key
+			segment.Map = initMap
+
+			if r := segment.ByteRange; r != nil {
+				if r.Offset < 0 {
+					r.Offset = rangeEnds[line]
+				}
+
+				rangeEnds[line] = r.Offset + r.Length
+			}
+
+			// Segments without their own date continue on from the previous one.
+			if segment.ProgramDateTime.IsZero() && !pdt.IsZero() && !segment.Discontinuity {
+				segment.ProgramDateTime = pdt
+			}
+
+			if !segment.ProgramDateTime.IsZero() {
+				pdt = segment.ProgramDateTime.Add(time.Duration(segment.Duration * float64(time.Second)))
+			}
+
+			p.Segments = append(p.Segments, segment)
+			segment = &Segment{}
+			continue
+		}
+
+		// Ignore comments.
+		if strings.Index(line, "#EXT") != 0 {
+			continue
+		}
+
+		name, value := splitTag(line)
+
+		if isPlaylistTag(name) {
+			p.Header = append(p.Header, line)
+
+			switch name {
+			case "EXT-X-VERSION":
+				p.Version, _ = strconv.Atoi(value)
+			case "EXT-X-TARGETDURATION":
+				p.TargetDuration, _ = strconv.Atoi(value)
+			case "EXT-X-MEDIA-SEQUENCE":
+				p.MediaSequence, _ = strconv.Atoi(value)
+			case "EXT-X-DISCONTINUITY-SEQUENCE":
+				p.DiscontinuitySequence, _ = strconv.Atoi(value)
+			case "EXT-X-PLAYLIST-TYPE":
+				p.PlaylistType = value
+			case "EXT-X-ENDLIST":
+				p.EndList = true
+			}
+
+			continue
+		}
+
+		segment.Tags = append(segment.Tags, line)
+
+		switch name {
+		case "EXTINF":
+			parts := strings.SplitN(value, ",", 2)
+			segment.Duration, _ = strconv.ParseFloat(parts[0], 64)
+
+			if len(parts) == 2 {
+				segment.Title = parts[1]
+			}
+		case "EXT-X-PROGRAM-DATE-TIME":
+			if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				segment.ProgramDateTime = t
+			}
+		case "EXT-X-DISCONTINUITY":
+			segment.Discontinuity = true
+		case "EXT-X-GAP":
+			segment.Gap = true
+		case "EXT-X-BYTERANGE":
+			segment.ByteRange = parseByteRange(value)
+		case "EXT-X-KEY":
+			key = line
+		case "EXT-X-MAP":
+			attrs := ParseAttributes(value)
+			initMap = &Map{URI: attrs["URI"]}
+
+			if r, ok := attrs["BYTERANGE"]; ok {
+				initMap.ByteRange = parseByteRange(r)
+
+				if initMap.ByteRange.Offset < 0 {
+					initMap.ByteRange.Offset = 0
+				}
+			}
+		}
+	}
+
+	return p
+}
+
+// ResolveURI returns ref as an absolute url relative to the playlist.
+func (p *MediaPlaylist) ResolveURI(ref string) string {
+	base, err := baseURL(p.URL)
+
+	if err != nil {
+		return ref
+	}
+
+	return resolveURL(base, ref)
+}
+
+// ParseAttributes parses an attribute list such as the value of
+// EXT-X-STREAM-INF. Quotes are removed from quoted strings, which may contain
+// commas.
+func ParseAttributes(value string) map[string]string {
+	attrs := make(map[string]string)
+
+	for len(value) > 0 {
+		eq := strings.Index(value, "=")
+
+		if eq < 0 {
+			break
+		}
+
+		name := strings.TrimSpace(value[:eq])
+		value = value[eq+1:]
+
+		var attr string
+
+		if strings.HasPrefix(value, "\"") {
+			end := strings.Index(value[1:], "\"")
+
+			if end < 0 {
+				end = len(value) - 1
+			}
+
+			attr = value[1 : end+1]
+			value = value[end+1:]
+
+			if len(value) > 0 {
+				value = value[1:]
+			}
+		}
+
+		comma := strings.Index(value, ",")
+
+		if comma < 0 {
+			comma = len(value)
+		}
+
+		if attr == "" {
+			attr = value[:comma]
+		}
+
+		attrs[name] = attr
+
+		if comma < len(value) {
+			value = value[comma+1:]
+		} else {
+			value = ""
+		}
+	}
+
+	return attrs
+}
+
+// parseByteRange parses a <length>[@<offset>] byte range. A missing offset is
+// returned as -1.
+func parseByteRange(value string) *ByteRange {
+	r := &ByteRange{Offset: -1}
+
+	parts := strings.SplitN(value, "@", 2)
+
+	r.Length, _ = strconv.ParseInt(parts[0], 10, 64)
+
+	if len(parts) == 2 {
+		r.Offset, _ = strconv.ParseInt(parts[1], 10, 64)
+	}
+
+	return r
+}
+
+// resolveURL returns ref as an absolute url relative to base.
+func resolveURL(base *url.URL, ref string) string {
+	u, err := url.Parse(ref)
+
+	if err != nil {
+		return ref
+	}
+
+	return base.ResolveReference(u).String()
+}
+
+// LastSegment returns the newest segment in the playlist or nil if it is empty.
+func (p *MediaPlaylist) LastSegment() *Segment {
+	if len(p.Segments) == 0 {
+		return nil
+	}
+
+	return p.Segments[len(p.Segments)-1]
+}
+
+// EdgeTime returns when the newest segment ends by its program date time, or
+// the zero time when it has none.
+func (p *MediaPlaylist) EdgeTime() time.Time {
+	last := p.LastSegment()
+
+	if last == nil || last.ProgramDateTime.IsZero() {
+		return time.Time{}
+	}
+
+	return last.ProgramDateTime.Add(time.Duration(last.Duration * float64(time.Second)))
+}
+
+// splitTag splits a tag line into its name and value.
+func splitTag(line string) (string, string) {
+	line = strings.TrimPrefix(line, "#")
+
+	i := strings.Index(line, ":")
+
+	if i < 0 {
+		return line, ""
+	}
+
+	return line[:i], line[i+1:]
+}
+
+// IsPlaylistTag checks if the line is a tag that applies to the whole playlist
+// rather than to the segment that follows it.
+func IsPlaylistTag(line string) bool {
+	if strings.Index(line, "#EXT") != 0 {
+		return false
+	}
+
+	name, _ := splitTag(line)
+
+	return isPlaylistTag(name)
+}
+
+// isPlaylistTag checks if name is a tag that applies to the whole playlist.
+func isPlaylistTag(name string) bool {
+	for _, tag := range playlistTags {
+		if tag == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsMasterPlaylist checks if rawData holds a master playlist rather than a
+// media playlist.
+func IsMasterPlaylist(rawData string) bool {
+	return strings.Contains(rawData, streamInf) || strings.Contains(rawData, "#EXT-X-MEDIA:")
+}
@@ -0,0 +1,154 @@
+package hls
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Selector picks a variant of a master playlist by its attributes rather
+// than its position, so that it finds the same rendition when the ladder
+// changes. The zero Selector matches every variant stream.
+type Selector struct {
+	// Number is the position of the variant as listed, counted from 1. 0
+	// leaves it to the other fields.
+	Number int
+
+	// Lowest picks the variant with the least bandwidth of those that match,
+	// otherwise the one with the most is picked.
+	Lowest bool
+
+	// Resolution matches the RESOLUTION attribute, e.g. 1280x720.
+	Resolution string
+
+	// BandwidthMax is the most BANDWIDTH a variant may have, 0 for no limit.
+	BandwidthMax int
+
+	// Codec matches a variant with a codec starting with it, e.g. hvc1 or
+	// avc1.64, ignoring case.
+	Codec string
+
+	// URLMatch matches the url of the variant.
+	URLMatch *regexp.Regexp
+}
+
+// ParseSelector reads the --variant value: a number counted from 1, highest
+// or lowest. An empty value returns nil.
+func ParseSelector(value string) (*Selector, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return nil, nil
+	case "highest":
+		return &Selector{}, nil
+	case "lowest":
+		return &Selector{Lowest: true}, nil
+	}
+
+	n, err := strconv.Atoi(value)
+
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("variant %q is not highest, lowest or a number from 1", value)
+	}
+
+	return &Selector{Number: n}, nil
+}
+
+// Select returns the index of the variant that the selector picks.
+func (s *Selector) Select(variants []*Variant) (int, error) {
+	if s.Number > 0 {
+		if s.Number > len(variants) {
+			return -1, fmt.Errorf("no variant %d, the playlist has %d", s.Number, len(variants))
+		}
+
+		if !s.matches(variants[s.Number-1]) {
+			return -1, fmt.Errorf("variant %d does not match %s", s.Number, s.criteria())
+		}
+
+		return s.Number - 1, nil
+	}
+
+	selected := -1
+
+	for i, v := range variants {
+		// Renditions are only picked by number, they have no bandwidth.
+		if v.Type != "" || !s.matches(v) {
+			continue
+		}
+
+		if selected < 0 {
+			selected = i
+			continue
+		}
+
+		best := variants[selected].Bandwidth
+
+		if (s.Lowest && v.Bandwidth < best) || (!s.Lowest && v.Bandwidth > best) {
+			selected = i
+		}
+	}
+
+	if selected < 0 {
+		return -1, fmt.Errorf("no variant matches %s", s.criteria())
+	}
+
+	return selected, nil
+}
+
+// matches reports whether v has the attributes the selector asks for.
+func (s *Selector) matches(v *Variant) bool {
+	if s.Resolution != "" && !strings.EqualFold(v.Resolution, s.Resolution) {
+		return false
+	}
+
+	if s.BandwidthMax > 0 && (v.Type != "" || v.Bandwidth > s.BandwidthMax) {
+		return false
+	}
+
+	if s.URLMatch != nil && !s.URLMatch.MatchString(v.URL) {
+		return false
+	}
+
+	if s.Codec != "" {
+		found := false
+
+		for _, codec := range strings.Split(v.Codecs, ",") {
+			if strings.HasPrefix(strings.ToLower(strings.TrimSpace(codec)), strings.ToLower(s.Codec)) {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// criteria describes what the selector matches for errors.
+func (s *Selector) criteria() string {
+	var criteria []string
+
+	if s.Resolution != "" {
+		criteria = append(criteria, "resolution "+s.Resolution)
+	}
+
+	if s.BandwidthMax > 0 {
+		criteria = append(criteria, fmt.Sprintf("bandwidth at most %d", s.BandwidthMax))
+	}
+
+	if s.Codec != "" {
+		criteria = append(criteria, "codec "+s.Codec)
+	}
+
+	if s.URLMatch != nil {
+		criteria = append(criteria, fmt.Sprintf("url matching %q", s.URLMatch))
+	}
+
+	if len(criteria) == 0 {
+		return "a variant stream"
+	}
+
+	return strings.Join(criteria, ", ")
+}
@@ -0,0 +1,108 @@
+package hls
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// ladder has an audio rendition followed by variants in no particular order
+// of bandwidth, so that the position of a variant is not its rank.
+const ladder = `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="English",URI="audio.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=2000000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",AUDIO="aud"
+v720.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=8000000,RESOLUTION=3840x2160,CODECS="hvc1.2.4.L153.B0,mp4a.40.2",AUDIO="aud"
+hevc/v2160.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360,CODECS="avc1.42c01e,mp4a.40.2",AUDIO="aud"
+v360.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=4500000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",AUDIO="aud"
+v1080.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="hvc1.2.4.L123.B0,mp4a.40.2",AUDIO="aud"
+hevc/v1080.m3u8
+`
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		value string
+		want  *Selector
+	}{
+		{"", nil},
+		{"highest", &Selector{}},
+		{"HIGHEST", &Selector{}},
+		{"lowest", &Selector{Lowest: true}},
+		{"1", &Selector{Number: 1}},
+		{"12", &Selector{Number: 12}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSelector(tt.value)
+
+		if err != nil {
+			t.Errorf("ParseSelector(%q) failed: %s", tt.value, err)
+			continue
+		}
+
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("ParseSelector(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"0", "-1", "first", "1.5", "best"} {
+		if got, err := ParseSelector(value); err == nil {
+			t.Errorf("ParseSelector(%q) = %+v, want an error", value, got)
+		}
+	}
+}
+
+func TestSelectorSelect(t *testing.T) {
+	master, err := ParseMaster("http://example.com/master.m3u8", ladder)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		selector Selector
+		want     string
+		err      string
+	}{
+		{"highest", Selector{}, "hevc/v2160.m3u8", ""},
+		{"lowest", Selector{Lowest: true}, "v360.m3u8", ""},
+		{"number", Selector{Number: 2}, "v720.m3u8", ""},
+		{"number of a rendition", Selector{Number: 1}, "audio.m3u8", ""},
+		{"number out of range", Selector{Number: 7}, "", "no variant 7, the playlist has 6"},
+		{"number not matching", Selector{Number: 2, Resolution: "640x360"}, "", "variant 2 does not match resolution 640x360"},
+		{"resolution", Selector{Resolution: "1920x1080"}, "hevc/v1080.m3u8", ""},
+		{"lowest with resolution", Selector{Resolution: "1920x1080", Lowest: true}, "v1080.m3u8", ""},
+		{"bandwidth max", Selector{BandwidthMax: 4500000}, "v1080.m3u8", ""},
+		{"codec", Selector{Codec: "AVC1"}, "v1080.m3u8", ""},
+		{"codec prefix", Selector{Codec: "avc1.42"}, "v360.m3u8", ""},
+		{"url match", Selector{URLMatch: regexp.MustCompile(`hevc/`), Lowest: true}, "hevc/v1080.m3u8", ""},
+		{"all criteria", Selector{Resolution: "1920x1080", BandwidthMax: 6000000, Codec: "hvc1"}, "hevc/v1080.m3u8", ""},
+		{"no match", Selector{Codec: "av01", BandwidthMax: 1000}, "", "no variant matches bandwidth at most 1000, codec av01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := tt.selector.Select(master.Variants)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Select = %d, %v, want an error containing %q", index, err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Select failed: %s", err)
+			}
+
+			if got := master.Variants[index].URL; got != "http://example.com/"+tt.want {
+				t.Errorf("Select picked %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
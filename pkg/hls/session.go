@@ -1,27 +1,33 @@
 package hls
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"time"
-
-	"github.com/moore0n/hlstail/pkg/tools"
+	"sync"
 )
 
-// Session Stores state information
+// Session Stores state information. Variant is the first of the selected
+// Variants, which are tailed side by side when there is more than one. When
+// the variant was picked by a Selector it is picked again each time the master
+// is refreshed.
 type Session struct {
-	URL     string
-	Master  *Master
-	Variant *Variant
+	URL      string
+	Fetcher  Fetcher
+	Master   *Master
+	Variant  *Variant
+	Variants []*Variant
+	Selector *Selector
 }
 
-// NewSession return a new session
-func NewSession(URL string) (*Session, error) {
+// NewSession return a new session, a nil fetcher uses DefaultFetcher.
+func NewSession(URL string, fetcher Fetcher) (*Session, error) {
 	sess := &Session{
-		URL: URL,
+		URL:     URL,
+		Fetcher: fetcher,
 	}
 
 	sess.Master = NewMaster(sess.URL)
+	sess.Master.Fetcher = fetcher
 
 	if err := sess.Master.Get(); err != nil {
 		return nil, err
@@ -30,53 +36,97 @@ func NewSession(URL string) (*Session, error) {
 	return sess, nil
 }
 
-// GetMasterPlaylistOptions return the possible playlist options.
-func (sess *Session) GetMasterPlaylistOptions(width int, selectedIndex int, showLoading bool) string {
-	sess.Master = NewMaster(sess.URL)
+// RefreshMaster requests the master playlist again. The previous variants are
+// kept if the request fails. With a Selector the variant is picked again, the
+// old one is kept and an error returned when nothing matches any more.
+func (sess *Session) RefreshMaster() error {
+	master := NewMaster(sess.URL)
+	master.Fetcher = sess.Fetcher
 
-	// Print the loading screen here before we make the request.
-	if showLoading {
-		tools.PrintLoading(width)
+	if err := master.Get(); err != nil {
+		return fmt.Errorf("refreshing the master playlist: %w", err)
 	}
 
-	if err := sess.Master.Get(); err != nil {
-		fmt.Println("error getting master playlist.")
-		return ""
+	sess.Master = master
+
+	if sess.Selector == nil {
+		return nil
 	}
 
-	output := new(bytes.Buffer)
+	index, err := sess.Selector.Select(master.Variants)
 
-	fmt.Fprint(output, tools.GetHeader(width, " Select a variant"), "\r\n")
-	fmt.Fprint(output, sess.Master.GetVariantList(selectedIndex))
-	fmt.Fprint(output, "\r\n", tools.GetFooter(width, ""))
+	if err != nil {
+		return fmt.Errorf("%s, still tailing %s", err, sess.Variant.Label())
+	}
 
-	fmt.Fprint(output, "\r\nactions: (enter)select variant (q)uit (r)efresh\r\n")
+	// The same variant keeps its reload state.
+	if master.Variants[index].URL != sess.Variant.URL {
+		sess.setVariants([]int{index})
+	}
 
-	return output.String()
+	return nil
+}
+
+// Select tails the variant picked by selector, and picks it again whenever
+// the master is refreshed.
+func (sess *Session) Select(selector *Selector) error {
+	index, err := selector.Select(sess.Master.Variants)
+
+	if err != nil {
+		return err
+	}
+
+	sess.Selector = selector
+	sess.setVariants([]int{index})
+
+	return nil
 }
 
 // SetVariant sets the variant used for requesting data
 func (sess *Session) SetVariant(index int) {
-	sess.Variant = sess.Master.Variants[index]
+	sess.SetVariants([]int{index})
 }
 
-// GetVariantPrintData return the last n segments of a variant.
-func (sess *Session) GetVariantPrintData(width int, count int) string {
-	output := new(bytes.Buffer)
+// SetVariants selects several variants to tail at once, replacing any
+// Selector.
+func (sess *Session) SetVariants(indexes []int) {
+	sess.Selector = nil
+	sess.setVariants(indexes)
+}
 
-	fmt.Fprint(output, tools.GetHeader(width, " Segment Data"))
+// setVariants sets the variants to tail.
+func (sess *Session) setVariants(indexes []int) {
+	sess.Variants = nil
 
-	if err := sess.Variant.Refresh(); err != nil {
-		fmt.Fprint(output, err.Error())
-	} else {
-		fmt.Fprint(output, sess.Variant.GetHeaderTagsToPrint())
-		fmt.Fprint(output, tools.GetSeparator(width, "-"))
-		fmt.Fprint(output, sess.Variant.GetSegmentsToPrint(count))
+	for _, index := range indexes {
+		sess.Variants = append(sess.Variants, sess.Master.Variants[index])
 	}
 
-	fmt.Fprint(output, "\r\n", tools.GetFooter(width, time.Now().UTC().Format(time.RFC3339)))
+	sess.Variant = sess.Variants[0]
+}
+
+// Reload fetches the latest segments of the selected variant.
+func (sess *Session) Reload(ctx context.Context) *Update {
+	return sess.Variant.Reload(ctx)
+}
+
+// ReloadAll fetches the latest segments of every selected variant at once.
+// The updates are in the order the variants were selected.
+func (sess *Session) ReloadAll(ctx context.Context) []*Update {
+	updates := make([]*Update, len(sess.Variants))
+
+	var wg sync.WaitGroup
+
+	for i, variant := range sess.Variants {
+		wg.Add(1)
+
+		go func(i int, variant *Variant) {
+			defer wg.Done()
+			updates[i] = variant.Reload(ctx)
+		}(i, variant)
+	}
 
-	fmt.Fprint(output, "\r\nactions: (q)uit (p)ause (r)esume (c)hange variant\r\n")
+	wg.Wait()
 
-	return output.String()
+	return updates
 }
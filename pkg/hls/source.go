@@ -0,0 +1,195 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StdinURL is the location that reads a playlist from standard input.
+const StdinURL = "-"
+
+// Location turns a playlist argument into a url. Local paths become absolute
+// file:// urls so relative uris inside the playlist resolve against them, and
+// StdinURL is returned as is.
+func Location(location string) string {
+	if location == StdinURL || strings.Contains(location, "://") {
+		return location
+	}
+
+	path, err := filepath.Abs(location)
+
+	if err != nil {
+		return location
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// baseURL returns the url that relative uris in the playlist at location
+// resolve against. Playlists read from standard input use the working
+// directory.
+func baseURL(location string) (*url.URL, error) {
+	if location == StdinURL {
+		wd, err := os.Getwd()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &url.URL{Scheme: "file", Path: filepath.ToSlash(wd) + "/"}, nil
+	}
+
+	return url.Parse(location)
+}
+
+// SourceFetcher picks a fetcher by the scheme of the url. Local paths and
+// file:// urls are read from disk, StdinURL from standard input and anything
+// else is handed to HTTP.
+type SourceFetcher struct {
+	HTTP  Fetcher
+	File  Fetcher
+	Stdin Fetcher
+}
+
+// Fetch reads url with the fetcher for its scheme.
+func (f *SourceFetcher) Fetch(ctx context.Context, url string) (*Response, error) {
+	switch {
+	case url == StdinURL:
+		return f.Stdin.Fetch(ctx, url)
+	case strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://"):
+		return f.HTTP.Fetch(ctx, url)
+	default:
+		return f.File.Fetch(ctx, url)
+	}
+}
+
+// FileFetcher reads playlists from local files. A file is only read again once
+// its modification time or size changes, so tailing a playlist a packager
+// writes to disk polls its mtime on every reload.
+type FileFetcher struct {
+	mu    sync.Mutex
+	files map[string]*fileState
+}
+
+// fileState is the last read of a file.
+type fileState struct {
+	modified time.Time
+	size     int64
+	body     []byte
+}
+
+// NewFileFetcher creates a FileFetcher.
+func NewFileFetcher() *FileFetcher {
+	return &FileFetcher{
+		files: make(map[string]*fileState),
+	}
+}
+
+// Fetch reads the file at the local path or file:// url.
+func (f *FileFetcher) Fetch(ctx context.Context, location string) (*Response, error) {
+	start := time.Now()
+
+	path, err := localPath(location)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state, ok := f.files[path]
+
+	if !ok || !state.modified.Equal(info.ModTime()) || state.size != info.Size() {
+		body, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		state = &fileState{modified: info.ModTime(), size: info.Size(), body: body}
+		f.files[path] = state
+	}
+
+	header := make(http.Header)
+	header.Set("Last-Modified", state.modified.UTC().Format(http.TimeFormat))
+
+	return &Response{
+		URL:        location,
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       state.body,
+		Fetched:    start,
+		Latency:    time.Since(start),
+	}, nil
+}
+
+// localPath returns the file path of a local path or file:// url.
+func localPath(location string) (string, error) {
+	if !strings.HasPrefix(location, "file://") {
+		return location, nil
+	}
+
+	u, err := url.Parse(location)
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.FromSlash(u.Path), nil
+}
+
+// ReaderFetcher serves a playlist read once from a reader, such as standard
+// input. Every fetch returns the same body.
+type ReaderFetcher struct {
+	reader io.Reader
+	once   sync.Once
+	body   []byte
+	err    error
+}
+
+// NewReaderFetcher creates a ReaderFetcher for r.
+func NewReaderFetcher(r io.Reader) *ReaderFetcher {
+	return &ReaderFetcher{reader: r}
+}
+
+// Fetch returns the contents of the reader.
+func (f *ReaderFetcher) Fetch(ctx context.Context, url string) (*Response, error) {
+	start := time.Now()
+
+	f.once.Do(func() {
+		f.body, f.err = ioutil.ReadAll(f.reader)
+	})
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &Response{
+		URL:        url,
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       f.body,
+		Fetched:    start,
+		Latency:    time.Since(start),
+	}, nil
+}
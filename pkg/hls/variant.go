@@ -2,12 +2,11 @@ package hls
 
 import (
 	"bytes"
-	"errors"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const streamInf = "#EXT-X-STREAM-INF:"
@@ -22,99 +21,120 @@ var headerTags = []string{
 	"EXT-X-I-FRAMES-ONLY",
 }
 
-// Variant is a struct for storing data about a variant.
+// Variant is a struct for storing data about a variant. Renditions from
+// EXT-X-MEDIA tags are variants too, with Type set to their media type.
 type Variant struct {
-	Tags             []string
-	URL              string
-	Resolution       string
-	Bandwidth        int
-	Codecs           string
-	Segments         [][]string
-	previousSegments [][]string
-	rawData          string
+	Tags       []string
+	URL        string
+	Type       string
+	Attributes map[string]string
+	Resolution string
+	Bandwidth  int
+	Codecs     string
+	Fetcher    Fetcher
+	Playlist   *MediaPlaylist
+	Response   *Response
 }
 
 // Process will loop through the tags and populate convenience properties.
 func (v *Variant) Process() {
 	for _, tag := range v.Tags {
 		if strings.Index(tag, streamInf) == 0 {
-			raw := strings.ReplaceAll(tag, streamInf, "")
-
-			// Split up the values based on their key=values which are comman delimited
-			parts := strings.Split(raw, ",")
-
-			for _, part := range parts {
-				// Catch the edge case where codes have a comma in the middle of them.
-				if strings.Index(part, "=") == -1 {
-					v.Codecs = fmt.Sprintf("%s%s", v.Codecs, strings.ReplaceAll(part, "\"", ""))
-					continue
-				}
-
-				kv := strings.Split(strings.Trim(part, " "), "=")
-
-				fmt.Println(kv)
-
-				key := kv[0]
-				val := kv[1]
-
-				switch key {
-				case "BANDWIDTH":
-					i, _ := strconv.Atoi(val)
-					v.Bandwidth = i
-				case "CODECS":
-					v.Codecs = strings.ReplaceAll(val, "\"", "")
-				case "RESOLUTION":
-					v.Resolution = val
-				}
-			}
+			v.Attributes = ParseAttributes(strings.ReplaceAll(tag, streamInf, ""))
+
+			v.Bandwidth, _ = strconv.Atoi(v.Attributes["BANDWIDTH"])
+			v.Codecs = v.Attributes["CODECS"]
+			v.Resolution = v.Attributes["RESOLUTION"]
 		}
 	}
 }
 
-// Get makes the http request to get the latest data.
-func (v *Variant) Get() error {
-	data, err := http.Get(v.URL)
+// Label names the variant by its resolution and bandwidth, or a rendition by
+// its type and name.
+func (v *Variant) Label() string {
+	if v.Type != "" {
+		return strings.ToLower(v.Type) + " " + v.Resolution
+	}
 
-	if err != nil {
-		return err
+	res := v.Resolution
+
+	if res == "" {
+		res = "audio-only"
 	}
 
-	defer data.Body.Close()
+	return res + " " + strconv.Itoa(v.Bandwidth)
+}
+
+// StreamInf returns the EXT-X-STREAM-INF or EXT-X-MEDIA tag that describes
+// the variant.
+func (v *Variant) StreamInf() string {
+	for _, tag := range v.Tags {
+		if strings.Index(tag, streamInf) == 0 || strings.Index(tag, "#EXT-X-MEDIA:") == 0 {
+			return tag
+		}
+	}
+
+	return ""
+}
+
+// Get makes the http request to get the latest data.
+func (v *Variant) Get() error {
+	return v.get(context.Background())
+}
+
+func (v *Variant) get(ctx context.Context) error {
+	resp, err := orDefault(v.Fetcher).Fetch(ctx, v.URL)
 
-	body, err := ioutil.ReadAll(data.Body)
+	v.Response = resp
 
 	if err != nil {
 		return err
 	}
 
-	v.rawData = string(body)
-	v.Segments = parseSegments(v.rawData)
+	v.Playlist = ParseMediaPlaylist(v.URL, string(resp.Body))
 
 	return nil
 }
 
-// Refresh gets fresh segments that can be processed
-func (v *Variant) Refresh() error {
-	// Store the previous data.
-	v.previousSegments = v.Segments
+// Reload gets fresh segments and describes what changed since the last load.
+func (v *Variant) Reload(ctx context.Context) *Update {
+	update := &Update{
+		Time:     time.Now(),
+		URL:      v.URL,
+		Variant:  v,
+		Previous: v.Playlist,
+	}
 
 	// Get new information
-	if err := v.Get(); err != nil {
-		return errors.New("Unable to get segments")
+	err := v.get(ctx)
+
+	update.Response = v.Response
+
+	// Recorded responses carry the time they were originally fetched.
+	if v.Response != nil {
+		update.Time = v.Response.Fetched
 	}
 
-	return nil
+	if err != nil {
+		update.Err = fmt.Errorf("unable to get segments: %w", err)
+		return update
+	}
+
+	update.Playlist = v.Playlist
+	update.Added, update.Removed, update.Tags = diffPlaylists(update.Previous, update.Playlist)
+
+	return update
 }
 
 // GetHeaderTagsToPrint returns a the header tags for printing.
-func (v *Variant) GetHeaderTagsToPrint() string {
-	// Get the first segment which would also hold the header data.
-	headSegment := filterHeadTags(v.Segments[0])
+func (u *Update) GetHeaderTagsToPrint() string {
+	// Get the playlist tags which hold the header data.
+	headSegment := filterHeadTags(u.Playlist.Header)
 
 	var previousHeadSegment []string
 
-	if len(v.previousSegments) > 0 {
-		previousHeadSegment = filterHeadTags(v.previousSegments[0])
+	if u.Previous != nil {
+		previousHeadSegment = filterHeadTags(u.Previous.Header)
 	}
 
 	// Build a buffer to manage appending the text.
@@ -147,14 +167,16 @@ func (v *Variant) GetHeaderTagsToPrint() string {
 }
 
 // GetSegmentsToPrint compiles the text list of segments to print.
-func (v *Variant) GetSegmentsToPrint(count int) string {
+func (u *Update) GetSegmentsToPrint(count int) string {
+	segments := u.Playlist.Segments
+
 	// Prevent out of range errors.
-	if count > len(v.Segments) {
-		count = len(v.Segments)
+	if count > len(segments) {
+		count = len(segments)
 	}
 
 	// Trim to the segments to the count that the user requested.
-	segments := v.Segments[len(v.Segments)-count:]
+	segments = segments[len(segments)-count:]
 
 	// Build a buffer to manage appending the text.
 	output := new(bytes.Buffer)
@@ -163,74 +185,20 @@ func (v *Variant) GetSegmentsToPrint(count int) string {
 	for i := 0; i < len(segments); i++ {
 		color := ""
 
-		if !segmentExists(v.previousSegments, segments[i]) {
+		if u.IsAdded(segments[i]) {
 			color = "\033[38;5;40m"
 		} else if i%2 == 0 {
 			// Gray
 			color = "\033[38;5;250m"
 		}
 
-		fmt.Fprintf(output, "\r\n%s%s\033[0m\r\n", color, strings.Join(segments[i], "\r\n"))
+		fmt.Fprintf(output, "\r\n%s%s\033[0m\r\n", color, strings.Join(segments[i].Lines(), "\r\n"))
 	}
 
 	return output.String()
 }
 
-// Parse the segments into slices for each group of segment data.
-func parseSegments(rawData string) [][]string {
-	// Make a slice to store the segments to be printed.
-	segments := make([][]string, 0)
-
-	lines := strings.Split(rawData, "\n")
-
-	var segment []string
-
-	// Loop over the lines and create variants.
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-
-		if line == "" {
-			continue
-		}
-
-		// We've hit the ts line so we need to push this segment into the list.
-		if strings.Index(line, "#") == 0 {
-			segment = append(segment, line)
-		} else {
-			segment = append(segment, line)
-
-			// Append to the master list of segments
-			segments = append(segments, segment)
-
-			// Create a new array.
-			segment = make([]string, 0)
-		}
-	}
-
-	return segments
-}
-
-// segmentExists Check if the elem exists in the prev list.
-func segmentExists(prev [][]string, elem []string) bool {
-	currentSegmentSource := filterSegmentSource(elem)
-
-	// If there was an issue getting the source just return false
-	if currentSegmentSource == "" {
-		return false
-	}
-
-	for i := 0; i < len(prev); i++ {
-		prevSegmentSource := filterSegmentSource(prev[i])
-
-		if currentSegmentSource == prevSegmentSource {
-			return true
-		}
-	}
-
-	return false
-}
-
-// Use the first segment and pull out the header specific tags to print.
+// Use the playlist tags and pull out the header specific tags to print.
 func filterHeadTags(segment []string) []string {
 
 	result := make([]string, 0)
@@ -255,13 +223,3 @@ func filterHeadTags(segment []string) []string {
 
 	return result
 }
-
-func filterSegmentSource(segment []string) string {
-	for _, val := range segment {
-		if strings.Index(val, "#") < 0 {
-			return val
-		}
-	}
-
-	return ""
-}
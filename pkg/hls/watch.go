@@ -0,0 +1,205 @@
+package hls
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultWatchInterval is used when WatchOptions does not set an interval.
+const defaultWatchInterval = 3 * time.Second
+
+// TagChange describes a playlist tag that was added, removed or changed value
+// between two reloads. Old and New hold the full tag lines, Old is empty for
+// added tags and New for removed ones.
+type TagChange struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// Update describes a single reload of a media playlist. When the reload fails
+// Err is set and only Time, URL and possibly Response are populated.
+type Update struct {
+	Time     time.Time
+	URL      string
+	Variant  *Variant
+	Response *Response
+	Playlist *MediaPlaylist
+	Previous *MediaPlaylist
+	Added    []*Segment
+	Removed  []*Segment
+	Tags     []TagChange
+	Err      error
+}
+
+// IsAdded checks if the segment was new in this reload.
+func (u *Update) IsAdded(segment *Segment) bool {
+	for _, s := range u.Added {
+		if s == segment {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WatchOptions configure Watch.
+type WatchOptions struct {
+	// Interval is the time to wait between reloads.
+	Interval time.Duration
+
+	// Variant is the zero based index of the variant to follow when the
+	// watched url is a master playlist.
+	Variant int
+
+	// Fetcher retrieves the playlists, DefaultFetcher is used when nil.
+	Fetcher Fetcher
+}
+
+// Watch follows the playlist at url and sends an Update for every reload
+// until ctx is cancelled, at which point the channel is closed. If url is a
+// master playlist the variant selected by opts is followed.
+func Watch(ctx context.Context, url string, opts WatchOptions) <-chan *Update {
+	updates := make(chan *Update)
+
+	interval := opts.Interval
+
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	go func() {
+		defer close(updates)
+
+		var variant *Variant
+
+		for {
+			var update *Update
+
+			if variant == nil {
+				v, err := resolveVariant(ctx, url, opts)
+
+				if err != nil {
+					update = &Update{Time: time.Now(), URL: url, Err: err}
+				}
+
+				variant = v
+			}
+
+			if variant != nil {
+				update = variant.Reload(ctx)
+			}
+
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates
+}
+
+// resolveVariant loads the playlist at url and returns the variant to follow.
+func resolveVariant(ctx context.Context, url string, opts WatchOptions) (*Variant, error) {
+	master := NewMaster(url)
+	master.Fetcher = opts.Fetcher
+
+	if err := master.get(ctx); err != nil {
+		return nil, err
+	}
+
+	if opts.Variant < 0 || opts.Variant >= len(master.Variants) {
+		return nil, errors.New("index out of range")
+	}
+
+	return master.Variants[opts.Variant], nil
+}
+
+// diffPlaylists compares two reloads of the same playlist. A nil previous
+// playlist treats every segment and tag as added.
+func diffPlaylists(previous *MediaPlaylist, current *MediaPlaylist) ([]*Segment, []*Segment, []TagChange) {
+	var prevSegments []*Segment
+	var prevHeader []string
+
+	if previous != nil {
+		prevSegments = previous.Segments
+		prevHeader = previous.Header
+	}
+
+	added := make([]*Segment, 0)
+	removed := make([]*Segment, 0)
+
+	for _, s := range current.Segments {
+		if !containsSegment(prevSegments, s) {
+			added = append(added, s)
+		}
+	}
+
+	for _, s := range prevSegments {
+		if !containsSegment(current.Segments, s) {
+			removed = append(removed, s)
+		}
+	}
+
+	return added, removed, diffTags(prevHeader, current.Header)
+}
+
+// diffTags compares the playlist tags of two reloads by name.
+func diffTags(previous []string, current []string) []TagChange {
+	changes := make([]TagChange, 0)
+
+	old := tagValues(previous)
+	cur := tagValues(current)
+
+	for _, line := range current {
+		name, _ := splitTag(line)
+
+		if prev, ok := old[name]; !ok {
+			changes = append(changes, TagChange{Name: name, New: line})
+		} else if prev != line {
+			changes = append(changes, TagChange{Name: name, Old: prev, New: line})
+		}
+	}
+
+	for _, line := range previous {
+		name, _ := splitTag(line)
+
+		if _, ok := cur[name]; !ok {
+			changes = append(changes, TagChange{Name: name, Old: line})
+		}
+	}
+
+	return changes
+}
+
+// tagValues maps each tag name to its line.
+func tagValues(tags []string) map[string]string {
+	values := make(map[string]string)
+
+	for _, line := range tags {
+		name, _ := splitTag(line)
+		values[name] = line
+	}
+
+	return values
+}
+
+// containsSegment checks if a segment with the same uri exists in the list.
+func containsSegment(segments []*Segment, segment *Segment) bool {
+	for _, s := range segments {
+		if s.URI == segment.URI {
+			return true
+		}
+	}
+
+	return false
+}
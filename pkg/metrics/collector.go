@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var durationBuckets = []float64{1, 2, 4, 6, 8, 10, 15, 30}
+
+// Collector turns playlist updates into hlstail metrics labelled by stream and
+// variant.
+type Collector struct {
+	mu     sync.Mutex
+	health map[string]*hls.Health
+
+	reloads         *Counter
+	reloadLatency   *Histogram
+	responses       *Counter
+	mediaSequence   *Gauge
+	liveEdgeLatency *Gauge
+	segmentDuration *Histogram
+	sinceNewSegment *Gauge
+	violations      *Counter
+}
+
+// NewCollector registers the hlstail metrics with r.
+func NewCollector(r *Registry) *Collector {
+	return &Collector{
+		health: make(map[string]*hls.Health),
+
+		reloads:         r.Counter("hlstail_reloads_total", "Number of media playlist reloads.", "stream", "variant"),
+		reloadLatency:   r.Histogram("hlstail_reload_duration_seconds", "Time taken to fetch the media playlist.", latencyBuckets, "stream", "variant"),
+		responses:       r.Counter("hlstail_http_responses_total", "Number of media playlist responses by HTTP status, failed requests use the code error.", "stream", "variant", "code"),
+		mediaSequence:   r.Gauge("hlstail_media_sequence", "Current EXT-X-MEDIA-SEQUENCE of the media playlist.", "stream", "variant"),
+		liveEdgeLatency: r.Gauge("hlstail_live_edge_latency_seconds", "Time between the end of the newest segment by program date time and the reload.", "stream", "variant"),
+		segmentDuration: r.Histogram("hlstail_segment_duration_seconds", "Duration of new segments.", durationBuckets, "stream", "variant"),
+		sinceNewSegment: r.Gauge("hlstail_seconds_since_last_new_segment", "Time since a reload last found new segments.", "stream", "variant"),
+		violations:      r.Counter("hlstail_health_violations_total", "Number of health rule violations.", "stream", "variant", "rule"),
+	}
+}
+
+// Observe records a reload of the variant of a stream.
+func (c *Collector) Observe(stream string, variant string, u *hls.Update) {
+	c.reloads.Inc(stream, variant)
+
+	if u.Response != nil {
+		c.reloadLatency.Observe(u.Response.Latency.Seconds(), stream, variant)
+		c.responses.Inc(stream, variant, strconv.Itoa(u.Response.StatusCode))
+	} else if u.Err != nil {
+		c.responses.Inc(stream, variant, "error")
+	}
+
+	for _, v := range c.checkHealth(stream, variant, u) {
+		c.violations.Inc(stream, variant, v.Rule)
+	}
+
+	if u.Err != nil {
+		return
+	}
+
+	c.mediaSequence.Set(float64(u.Playlist.MediaSequence), stream, variant)
+
+	for _, s := range u.Added {
+		c.segmentDuration.Observe(s.Duration, stream, variant)
+	}
+
+	if edge := u.Playlist.EdgeTime(); !edge.IsZero() {
+		c.liveEdgeLatency.Set(u.Time.Sub(edge).Seconds(), stream, variant)
+	}
+}
+
+// checkHealth runs the health rules of a variant against the update,
+// registering the gauges that depend on them the first time the variant is
+// seen.
+func (c *Collector) checkHealth(stream string, variant string, u *hls.Update) []hls.Violation {
+	key := stream + "\xff" + variant
+
+	c.mu.Lock()
+
+	health, ok := c.health[key]
+
+	if !ok {
+		health = hls.NewHealth()
+		c.health[key] = health
+	}
+
+	violations := health.Check(u)
+
+	// The registry lock is taken while writing gauge funcs, so it must never
+	// be taken while holding ours.
+	c.mu.Unlock()
+
+	if !ok {
+		c.sinceNewSegment.SetFunc(func() float64 {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			if health.LastChange().IsZero() {
+				return 0
+			}
+
+			return time.Since(health.LastChange()).Seconds()
+		}, stream, variant)
+	}
+
+	return violations
+}
@@ -0,0 +1,267 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types in the Prometheus text format.
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// Registry holds metric families and writes them in the Prometheus text
+// exposition format. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// family is every series of a metric.
+type family struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+	order   []string
+}
+
+// series is a single combination of label values.
+type series struct {
+	labelValues []string
+	value       float64
+	fn          func() float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	r *Registry
+	f *family
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	r *Registry
+	f *family
+}
+
+// Histogram counts observations into buckets.
+type Histogram struct {
+	r *Registry
+	f *family
+}
+
+// Counter registers a new counter.
+func (r *Registry) Counter(name string, help string, labels ...string) *Counter {
+	return &Counter{r: r, f: r.register(name, help, typeCounter, labels, nil)}
+}
+
+// Gauge registers a new gauge.
+func (r *Registry) Gauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{r: r, f: r.register(name, help, typeGauge, labels, nil)}
+}
+
+// Histogram registers a new histogram with the upper bounds of its buckets.
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{r: r, f: r.register(name, help, typeHistogram, labels, buckets)}
+}
+
+func (r *Registry) register(name string, help string, typ string, labels []string, buckets []float64) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f := &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+
+	r.families = append(r.families, f)
+
+	return f
+}
+
+// Add increases the counter for the label values by v.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+
+	c.f.get(labelValues).value += v
+}
+
+// Inc increases the counter for the label values by one.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Set sets the gauge for the label values.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.r.mu.Lock()
+	defer g.r.mu.Unlock()
+
+	s := g.f.get(labelValues)
+	s.value = v
+	s.fn = nil
+}
+
+// SetFunc makes the gauge for the label values call fn every time the metrics
+// are written.
+func (g *Gauge) SetFunc(fn func() float64, labelValues ...string) {
+	g.r.mu.Lock()
+	defer g.r.mu.Unlock()
+
+	g.f.get(labelValues).fn = fn
+}
+
+// Observe adds v to the histogram for the label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.r.mu.Lock()
+	defer h.r.mu.Unlock()
+
+	s := h.f.get(labelValues)
+
+	for i, bound := range h.f.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+
+	s.sum += v
+	s.count++
+}
+
+// get returns the series for the label values, creating it if needed.
+func (f *family) get(labelValues []string) *series {
+	key := strings.Join(labelValues, "\xff")
+
+	s, ok := f.series[key]
+
+	if !ok {
+		s = &series{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(f.buckets)),
+		}
+
+		f.series[key] = s
+		f.order = append(f.order, key)
+	}
+
+	return s
+}
+
+// WriteTo writes every metric in the text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	output := new(bytes.Buffer)
+
+	for _, f := range r.families {
+		if len(f.order) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(output, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(output, "# TYPE %s %s\n", f.name, f.typ)
+
+		for _, key := range f.order {
+			s := f.series[key]
+
+			if f.typ != typeHistogram {
+				value := s.value
+
+				if s.fn != nil {
+					value = s.fn()
+				}
+
+				fmt.Fprintf(output, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), formatValue(value))
+				continue
+			}
+
+			for i, bound := range f.buckets {
+				fmt.Fprintf(output, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.labelValues, "le", formatValue(bound)), s.counts[i])
+			}
+
+			fmt.Fprintf(output, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.labelValues, "le", "+Inf"), s.count)
+			fmt.Fprintf(output, "%s_sum%s %s\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), formatValue(s.sum))
+			fmt.Fprintf(output, "%s_count%s %d\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), s.count)
+		}
+	}
+
+	return output.WriteTo(w)
+}
+
+// ServeHTTP serves the metrics to a Prometheus scrape.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// formatLabels builds the label set of a series with an optional extra label.
+func formatLabels(names []string, values []string, extraName string, extraValue string) string {
+	pairs := make([]string, 0, len(names)+1)
+
+	for i, name := range names {
+		value := ""
+
+		if i < len(values) {
+			value = values[i]
+		}
+
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabel(value)))
+	}
+
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extraName, extraValue))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ","))
+}
+
+// escapeLabel escapes a label value as required by the text format.
+func escapeLabel(value string) string {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "\"", "\\\"")
+	value = strings.ReplaceAll(value, "\n", "\\n")
+
+	return value
+}
+
+// formatValue formats a sample value.
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
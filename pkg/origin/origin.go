@@ -0,0 +1,192 @@
+package origin
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// defaultWindow is the number of segments in a live playlist when none is set.
+const defaultWindow = 6
+
+// Options configure an Origin.
+type Options struct {
+	// Window is the number of segments in each live playlist.
+	Window int
+
+	// TargetDuration overrides EXT-X-TARGETDURATION, the longest segment
+	// rounded up is used when it is zero.
+	TargetDuration int
+
+	// Discontinuity marks the first segment of every loop with
+	// EXT-X-DISCONTINUITY.
+	Discontinuity bool
+
+	// PartDuration splits segments into LL-HLS parts when it is above zero.
+	PartDuration time.Duration
+}
+
+// Origin serves a VOD playlist and the files next to it as a live stream. Each
+// media playlist becomes a sliding window that loops over its segments, and
+// every other request is served from the directory of the playlist.
+type Origin struct {
+	opts    Options
+	name    string
+	streams map[string]*stream
+	files   http.Handler
+}
+
+// NewOrigin loads the master or media playlist at path. The media playlists
+// and segments it references must be local files below its directory for
+// them to be served.
+func NewOrigin(file string, opts Options) (*Origin, error) {
+	if opts.Window <= 0 {
+		opts.Window = defaultWindow
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(file))
+
+	if err != nil {
+		return nil, err
+	}
+
+	master := hls.NewMaster(file)
+
+	if err := master.Get(); err != nil {
+		return nil, err
+	}
+
+	o := &Origin{
+		opts:    opts,
+		name:    filepath.ToSlash(filepath.Base(file)),
+		streams: make(map[string]*stream),
+		files:   http.FileServer(http.Dir(dir)),
+	}
+
+	start := time.Now()
+
+	for _, variant := range master.Variants {
+		if err := variant.Get(); err != nil {
+			return nil, err
+		}
+
+		name, err := relativePath(dir, variant.URL)
+
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := newStream(variant.Playlist, start, opts)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		o.streams["/"+name] = s
+	}
+
+	return o, nil
+}
+
+// Playlists returns the url paths of the playlists being served, the one that
+// was loaded first.
+func (o *Origin) Playlists() []string {
+	paths := make([]string, 0, len(o.streams)+1)
+
+	for p := range o.streams {
+		if p != "/"+o.name {
+			paths = append(paths, p)
+		}
+	}
+
+	sort.Strings(paths)
+
+	return append([]string{"/" + o.name}, paths...)
+}
+
+// ServeHTTP writes the live window of a media playlist or serves a file.
+func (o *Origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s, ok := o.streams[path.Clean(r.URL.Path)]
+
+	if !ok {
+		o.files.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	fmt.Fprint(w, s.playlist(time.Now()))
+}
+
+// relativePath returns the path of the file:// url below dir using forward
+// slashes.
+func relativePath(dir string, location string) (string, error) {
+	u, err := url.Parse(location)
+
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("%s is not a local file", location)
+	}
+
+	rel, err := filepath.Rel(dir, filepath.FromSlash(u.Path))
+
+	if err != nil {
+		return "", err
+	}
+
+	rel = filepath.ToSlash(rel)
+
+	if strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s is outside of %s", location, dir)
+	}
+
+	return rel, nil
+}
+
+// segmentSize returns the number of bytes in a segment, or -1 if it is not a
+// local file.
+func segmentSize(p *hls.MediaPlaylist, s *hls.Segment) int64 {
+	if s.ByteRange != nil {
+		return s.ByteRange.Length
+	}
+
+	u, err := url.Parse(p.ResolveURI(s.URI))
+
+	if err != nil || u.Scheme != "file" {
+		return -1
+	}
+
+	info, err := os.Stat(filepath.FromSlash(u.Path))
+
+	if err != nil {
+		return -1
+	}
+
+	return info.Size()
+}
+
+// targetDuration returns the longest segment rounded up.
+func targetDuration(segments []*hls.Segment) int {
+	target := 0
+
+	for _, s := range segments {
+		if d := int(math.Ceil(s.Duration)); d > target {
+			target = d
+		}
+	}
+
+	return target
+}
@@ -0,0 +1,331 @@
+package origin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// generatedTags are written by the stream itself rather than copied from the
+// source playlist.
+var generatedTags = []string{
+	"EXTM3U",
+	"EXT-X-VERSION",
+	"EXT-X-TARGETDURATION",
+	"EXT-X-MEDIA-SEQUENCE",
+	"EXT-X-DISCONTINUITY-SEQUENCE",
+	"EXT-X-PLAYLIST-TYPE",
+	"EXT-X-ENDLIST",
+	"EXT-X-SERVER-CONTROL",
+	"EXT-X-PART-INF",
+	"EXT-X-SKIP",
+	"EXT-X-PRELOAD-HINT",
+	"EXT-X-RENDITION-REPORT",
+	"EXTINF",
+	"EXT-X-PROGRAM-DATE-TIME",
+	"EXT-X-DISCONTINUITY",
+	"EXT-X-BYTERANGE",
+	"EXT-X-KEY",
+	"EXT-X-MAP",
+	"EXT-X-GAP",
+	"EXT-X-PART",
+}
+
+// stream is a media playlist played as a live stream. Segment k of the stream
+// is source segment k modulo the number of segments, so the media sequence
+// keeps counting up as the source loops.
+type stream struct {
+	source *hls.MediaPlaylist
+	opts   Options
+	target int
+	epoch  time.Time
+	starts []float64
+	total  float64
+	sizes  []int64
+	discs  []int
+}
+
+// newStream prepares to loop source. The stream begins with a full window of
+// segments available at start.
+func newStream(source *hls.MediaPlaylist, start time.Time, opts Options) (*stream, error) {
+	n := len(source.Segments)
+
+	if n == 0 {
+		return nil, errors.New("the playlist has no segments")
+	}
+
+	s := &stream{
+		source: source,
+		opts:   opts,
+		target: opts.TargetDuration,
+		starts: make([]float64, n),
+		sizes:  make([]int64, n),
+		discs:  make([]int, n+1),
+	}
+
+	for i, seg := range source.Segments {
+		if seg.Duration <= 0 {
+			return nil, fmt.Errorf("segment %d has no duration", seg.Sequence)
+		}
+
+		s.starts[i] = s.total
+		s.total += seg.Duration
+		s.sizes[i] = -1
+
+		if opts.PartDuration > 0 {
+			s.sizes[i] = segmentSize(source, seg)
+		}
+
+		s.discs[i+1] = s.discs[i]
+
+		if seg.Discontinuity {
+			s.discs[i+1]++
+		}
+	}
+
+	if s.target <= 0 {
+		s.target = targetDuration(source.Segments)
+	}
+
+	// Go back far enough that the first window is already complete.
+	lead := s.total
+
+	if opts.Window < n {
+		lead = s.starts[opts.Window]
+	}
+
+	s.epoch = start.Add(-seconds(lead))
+
+	return s, nil
+}
+
+// playlist returns the live window of the stream at now.
+func (s *stream) playlist(now time.Time) string {
+	n := len(s.source.Segments)
+
+	elapsed := now.Sub(s.epoch).Seconds()
+	loop := int(elapsed / s.total)
+	progress := elapsed - float64(loop)*s.total
+
+	// The number of segments that have finished in this loop.
+	done := sort.Search(n, func(i int) bool {
+		return s.starts[i]+s.source.Segments[i].Duration > progress
+	})
+
+	end := loop*n + done
+	first := end - s.opts.Window
+
+	if first < 0 {
+		first = 0
+	}
+
+	output := new(bytes.Buffer)
+
+	fmt.Fprint(output, "#EXTM3U\n")
+
+	if s.source.Version > 0 {
+		fmt.Fprintf(output, "#EXT-X-VERSION:%d\n", s.source.Version)
+	}
+
+	fmt.Fprintf(output, "#EXT-X-TARGETDURATION:%d\n", s.target)
+
+	part := s.opts.PartDuration.Seconds()
+
+	if part > 0 {
+		fmt.Fprintf(output, "#EXT-X-SERVER-CONTROL:PART-HOLD-BACK=%.3f\n", 3*part)
+		fmt.Fprintf(output, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", part)
+	}
+
+	fmt.Fprintf(output, "#EXT-X-MEDIA-SEQUENCE:%d\n", first)
+
+	if d := s.discontinuitiesBefore(first); d > 0 {
+		fmt.Fprintf(output, "#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", d)
+	}
+
+	for _, line := range s.source.Header {
+		if !isGenerated(line) {
+			fmt.Fprintln(output, line)
+		}
+	}
+
+	for k := first; k < end; k++ {
+		seg := s.source.Segments[k%n]
+
+		for _, line := range s.segmentTags(k, k == first) {
+			fmt.Fprintln(output, line)
+		}
+
+		pdt := s.epoch.Add(seconds(float64(k/n)*s.total + s.starts[k%n]))
+		fmt.Fprintf(output, "#EXT-X-PROGRAM-DATE-TIME:%s\n", pdt.UTC().Format("2006-01-02T15:04:05.000Z"))
+
+		// Parts are only listed close to the live edge.
+		if part > 0 && k >= end-2 {
+			s.writeParts(output, k%n, seg.Duration)
+		}
+
+		fmt.Fprintf(output, "#EXTINF:%.3f,%s\n", seg.Duration, seg.Title)
+
+		if r := seg.ByteRange; r != nil {
+			fmt.Fprintf(output, "#EXT-X-BYTERANGE:%d@%d\n", r.Length, r.Offset)
+		}
+
+		if seg.Gap {
+			fmt.Fprint(output, "#EXT-X-GAP\n")
+		}
+
+		fmt.Fprintln(output, seg.URI)
+	}
+
+	// The parts of the segment that is still being produced.
+	if part > 0 && s.sizes[done] >= 0 && progress-s.starts[done] >= part {
+		for _, line := range s.segmentTags(end, end == first) {
+			fmt.Fprintln(output, line)
+		}
+
+		s.writeParts(output, done, progress-s.starts[done])
+	}
+
+	return output.String()
+}
+
+// segmentTags returns the tags before the EXTINF of segment k. The key and map
+// are repeated when they change or at the start of the window.
+func (s *stream) segmentTags(k int, first bool) []string {
+	n := len(s.source.Segments)
+	seg := s.source.Segments[k%n]
+
+	var prev *hls.Segment
+
+	if !first && k > 0 {
+		prev = s.source.Segments[(k-1)%n]
+	}
+
+	var lines []string
+
+	if seg.Discontinuity || (s.opts.Discontinuity && k > 0 && k%n == 0) {
+		lines = append(lines, "#EXT-X-DISCONTINUITY")
+	}
+
+	if prev == nil || prev.Key != seg.Key {
+		switch {
+		case seg.Key != "":
+			lines = append(lines, seg.Key)
+		case prev != nil:
+			lines = append(lines, "#EXT-X-KEY:METHOD=NONE")
+		}
+	}
+
+	if seg.Map != nil && (prev == nil || !sameMap(prev.Map, seg.Map)) {
+		line := fmt.Sprintf("#EXT-X-MAP:URI=%q", seg.Map.URI)
+
+		if r := seg.Map.ByteRange; r != nil {
+			line += fmt.Sprintf(",BYTERANGE=\"%d@%d\"", r.Length, r.Offset)
+		}
+
+		lines = append(lines, line)
+	}
+
+	for _, tag := range seg.Tags {
+		if !isGenerated(tag) {
+			lines = append(lines, tag)
+		}
+	}
+
+	return lines
+}
+
+// writeParts writes the parts of source segment i that finish within
+// duration seconds. Parts are equal slices of the segment's bytes.
+func (s *stream) writeParts(output *bytes.Buffer, i int, duration float64) {
+	seg := s.source.Segments[i]
+	size := s.sizes[i]
+
+	if size < 0 {
+		return
+	}
+
+	part := s.opts.PartDuration.Seconds()
+	count := int(math.Ceil(seg.Duration/part - 1e-9))
+
+	var offset int64
+
+	if seg.ByteRange != nil {
+		offset = seg.ByteRange.Offset
+	}
+
+	for j := 0; j < count; j++ {
+		length := math.Min(part, seg.Duration-float64(j)*part)
+
+		if float64(j)*part+length > duration+1e-9 {
+			return
+		}
+
+		from := offset + size*int64(j)/int64(count)
+		to := offset + size*int64(j+1)/int64(count)
+
+		fmt.Fprintf(output, "#EXT-X-PART:DURATION=%.3f,URI=%q,BYTERANGE=\"%d@%d\"", length, seg.URI, to-from, from)
+
+		if j == 0 {
+			fmt.Fprint(output, ",INDEPENDENT=YES")
+		}
+
+		fmt.Fprint(output, "\n")
+	}
+}
+
+// discontinuitiesBefore returns the number of discontinuities before segment
+// k of the stream.
+func (s *stream) discontinuitiesBefore(k int) int {
+	n := len(s.source.Segments)
+
+	count := k/n*s.discs[n] + s.discs[k%n]
+
+	// Loops are only marked when the first segment isn't a discontinuity
+	// already.
+	if s.opts.Discontinuity && !s.source.Segments[0].Discontinuity && k > 0 {
+		count += (k - 1) / n
+	}
+
+	return count
+}
+
+// isGenerated reports whether the tag on line is written by the stream.
+func isGenerated(line string) bool {
+	name := strings.SplitN(strings.TrimPrefix(line, "#"), ":", 2)[0]
+
+	for _, tag := range generatedTags {
+		if tag == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameMap reports whether a and b are the same initialization section.
+func sameMap(a, b *hls.Map) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if a.URI != b.URI {
+		return false
+	}
+
+	if a.ByteRange == nil || b.ByteRange == nil {
+		return a.ByteRange == b.ByteRange
+	}
+
+	return *a.ByteRange == *b.ByteRange
+}
+
+// seconds converts a number of seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the type of resource a request is for.
+type Kind string
+
+// The kinds of request faults can be applied to.
+const (
+	KindAny     Kind = "all"
+	KindMaster  Kind = "master"
+	KindMedia   Kind = "media"
+	KindSegment Kind = "segment"
+	KindKey     Kind = "key"
+)
+
+// The faults that can be injected.
+const (
+	FaultLatency  = "latency"
+	FaultStatus   = "status"
+	FaultTruncate = "truncate"
+	FaultStale    = "stale"
+	FaultDrop     = "drop"
+	FaultJump     = "jump"
+)
+
+// Fault is a change made to a response, with the probability of it being
+// applied to each request of its kind.
+type Fault struct {
+	Kind        Kind
+	Name        string
+	Probability float64
+
+	// Latency is the delay added by a latency fault.
+	Latency time.Duration
+
+	// Status is the response code of a status fault.
+	Status int
+
+	// Fraction is the part of the body kept by a truncate fault.
+	Fraction float64
+
+	// Jump is the amount a jump fault adds to the media sequence.
+	Jump int
+}
+
+// ParseFault parses a fault in the form kind:name[=value][@probability], for
+// example segment:status=503@0.1 or media:latency=2s. The probability is 1
+// when it is left out.
+func ParseFault(spec string) (*Fault, error) {
+	parts := strings.SplitN(spec, ":", 2)
+
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("fault %q is not in the form kind:name[=value][@probability]", spec)
+	}
+
+	f := &Fault{Kind: Kind(parts[0]), Probability: 1}
+
+	switch f.Kind {
+	case KindAny, KindMaster, KindMedia, KindSegment, KindKey:
+	default:
+		return nil, fmt.Errorf("fault %q has an unknown kind, use all, master, media, segment or key", spec)
+	}
+
+	rest := parts[1]
+
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		p, err := strconv.ParseFloat(rest[i+1:], 64)
+
+		// Written this way round so that NaN is rejected too.
+		if err != nil || !(p >= 0 && p <= 1) {
+			return nil, fmt.Errorf("fault %q has a probability outside of 0 to 1", spec)
+		}
+
+		f.Probability = p
+		rest = rest[:i]
+	}
+
+	f.Name = rest
+	value := ""
+
+	if i := strings.Index(rest, "="); i >= 0 {
+		f.Name = rest[:i]
+		value = rest[i+1:]
+	}
+
+	var err error
+
+	switch f.Name {
+	case FaultLatency:
+		f.Latency, err = time.ParseDuration(value)
+	case FaultStatus:
+		f.Status, err = strconv.Atoi(value)
+
+		if err == nil && (f.Status < 100 || f.Status > 599) {
+			err = fmt.Errorf("%d is not an HTTP status", f.Status)
+		}
+	case FaultTruncate:
+		f.Fraction = 0.5
+
+		if value != "" {
+			f.Fraction, err = strconv.ParseFloat(value, 64)
+		}
+
+		if err == nil && !(f.Fraction >= 0 && f.Fraction <= 1) {
+			err = fmt.Errorf("%s is not a fraction from 0 to 1", value)
+		}
+	case FaultJump:
+		f.Jump = 1
+
+		if value != "" {
+			f.Jump, err = strconv.Atoi(value)
+		}
+	case FaultStale, FaultDrop:
+		if value != "" {
+			err = fmt.Errorf("%s does not take a value", f.Name)
+		}
+	default:
+		err = fmt.Errorf("unknown fault %q", f.Name)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("fault %q: %w", spec, err)
+	}
+
+	// Only playlists can be replayed or have their segments changed.
+	if (f.Name == FaultStale || f.Name == FaultDrop || f.Name == FaultJump) && f.Kind != KindMedia && f.Kind != KindMaster && f.Kind != KindAny {
+		return nil, fmt.Errorf("fault %q only applies to playlists", spec)
+	}
+
+	if (f.Name == FaultDrop || f.Name == FaultJump) && f.Kind != KindMedia {
+		return nil, fmt.Errorf("fault %q only applies to media playlists", spec)
+	}
+
+	return f, nil
+}
+
+// applies reports whether the fault is for requests of kind.
+func (f *Fault) applies(kind Kind) bool {
+	return f.Kind == KindAny || f.Kind == kind
+}
+
+// String returns the fault in the form it is parsed from.
+func (f *Fault) String() string {
+	value := ""
+
+	switch f.Name {
+	case FaultLatency:
+		value = "=" + f.Latency.String()
+	case FaultStatus:
+		value = "=" + strconv.Itoa(f.Status)
+	case FaultTruncate:
+		value = "=" + strconv.FormatFloat(f.Fraction, 'f', -1, 64)
+	case FaultJump:
+		value = "=" + strconv.Itoa(f.Jump)
+	}
+
+	return fmt.Sprintf("%s:%s%s@%s", f.Kind, f.Name, value, strconv.FormatFloat(f.Probability, 'f', -1, 64))
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseFault(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Fault
+	}{
+		{"segment:status=503@0.1", Fault{Kind: KindSegment, Name: FaultStatus, Probability: 0.1, Status: 503}},
+		{"media:latency=2s", Fault{Kind: KindMedia, Name: FaultLatency, Probability: 1, Latency: 2 * time.Second}},
+		{"segment:truncate", Fault{Kind: KindSegment, Name: FaultTruncate, Probability: 1, Fraction: 0.5}},
+		{"segment:truncate=0", Fault{Kind: KindSegment, Name: FaultTruncate, Probability: 1, Fraction: 0}},
+		{"all:truncate=1@0", Fault{Kind: KindAny, Name: FaultTruncate, Probability: 0, Fraction: 1}},
+		{"media:stale@0.2", Fault{Kind: KindMedia, Name: FaultStale, Probability: 0.2}},
+		{"master:stale", Fault{Kind: KindMaster, Name: FaultStale, Probability: 1}},
+		{"media:drop", Fault{Kind: KindMedia, Name: FaultDrop, Probability: 1}},
+		{"media:jump", Fault{Kind: KindMedia, Name: FaultJump, Probability: 1, Jump: 1}},
+		{"media:jump=5", Fault{Kind: KindMedia, Name: FaultJump, Probability: 1, Jump: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			f, err := ParseFault(tt.spec)
+
+			if err != nil {
+				t.Fatalf("ParseFault(%q) failed: %s", tt.spec, err)
+			}
+
+			if *f != tt.want {
+				t.Errorf("ParseFault(%q) = %+v, want %+v", tt.spec, *f, tt.want)
+			}
+
+			// The fault prints in the form it is parsed from.
+			again, err := ParseFault(f.String())
+
+			if err != nil || *again != *f {
+				t.Errorf("ParseFault(%q) = %+v, %v, want %+v", f.String(), again, err, *f)
+			}
+		})
+	}
+}
+
+func TestParseFaultErrors(t *testing.T) {
+	for _, spec := range []string{
+		"status=503",
+		"video:status=503",
+		"segment:status=503@1.5",
+		"segment:status=503@-0.1",
+		"segment:status=503@NaN",
+		"segment:status=99",
+		"segment:status=abc",
+		"media:latency=soon",
+		"segment:truncate=1.5",
+		"segment:truncate=-0.2",
+		"segment:truncate=NaN",
+		"segment:truncate=half",
+		"media:stale=1",
+		"segment:stale",
+		"master:drop",
+		"all:jump",
+		"media:explode",
+	} {
+		if f, err := ParseFault(spec); err == nil {
+			t.Errorf("ParseFault(%q) = %+v, want an error", spec, *f)
+		}
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	tests := []struct {
+		n        int
+		fraction float64
+		want     int
+	}{
+		{100, 0.5, 50},
+		{100, 0, 0},
+		{100, 1, 100},
+		{100, 1.5, 100},
+		{100, -0.2, 0},
+		{100, math.NaN(), 0},
+		{0, 0.5, 0},
+	}
+
+	for _, tt := range tests {
+		if got := truncated(tt.n, tt.fraction); got != tt.want {
+			t.Errorf("truncated(%d, %v) = %d, want %d", tt.n, tt.fraction, got, tt.want)
+		}
+	}
+}
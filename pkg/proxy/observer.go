@@ -0,0 +1,303 @@
+package proxy
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// The number of requests and switches an Observer keeps.
+const (
+	maxRequests = 500
+	maxSwitches = 50
+)
+
+// Track is a media playlist the player has loaded and its position in it.
+type Track struct {
+	URL      string
+	Variant  *hls.Variant
+	Playlist *hls.MediaPlaylist
+
+	// Reloads counts the requests for the playlist, Interval is the time
+	// between the last two and Average the mean time between all of them.
+	Reloads    int
+	LastReload time.Time
+	Interval   time.Duration
+	Average    time.Duration
+
+	// LastSegment is the newest segment the player requested from the
+	// playlist.
+	LastSegment *hls.Segment
+	LastRequest time.Time
+
+	// Behind is the number of segments, and Distance the duration, between the
+	// start of the last requested segment and the end of the playlist when it
+	// was requested.
+	Behind   int
+	Distance time.Duration
+}
+
+// Label names the track by its rendition name or resolution and bandwidth.
+func (t *Track) Label() string {
+	if t.Variant == nil {
+		return t.URL[strings.LastIndex(t.URL, "/")+1:]
+	}
+
+	return t.Variant.Label()
+}
+
+// IsVariant reports whether the track is a variant stream rather than an
+// audio or subtitle rendition, only variants take part in ABR switches.
+func (t *Track) IsVariant() bool {
+	return t.Variant == nil || t.Variant.Type == ""
+}
+
+// Switch is the player moving from one variant to another, named by their
+// labels.
+type Switch struct {
+	Time time.Time
+	From string
+	To   string
+}
+
+// Observation is a request and the segment it was for, if it could be found
+// in a playlist the player loaded.
+type Observation struct {
+	*Request
+
+	Track    string
+	Segment  *hls.Segment
+	Behind   int
+	Distance time.Duration
+
+	// Switch is set when the segment is from a different variant than the
+	// previous one.
+	Switch *Switch
+}
+
+// Report is a copy of what an Observer has seen.
+type Report struct {
+	Master   string
+	Current  *Track
+	Tracks   []*Track
+	Switches []Switch
+	Requests []*Observation
+}
+
+// Observer follows the requests a player makes through a Proxy to work out
+// what it is playing. Pass the requests from the Observe option of the proxy
+// to Observe.
+type Observer struct {
+	mu       sync.Mutex
+	master   string
+	variants map[string]*hls.Variant
+	tracks   map[string]*Track
+	current  *Track
+	switches []Switch
+	requests []*Observation
+	changed  chan struct{}
+}
+
+// NewObserver creates an Observer.
+func NewObserver() *Observer {
+	return &Observer{
+		variants: make(map[string]*hls.Variant),
+		tracks:   make(map[string]*Track),
+		changed:  make(chan struct{}, 1),
+	}
+}
+
+// Changed receives a value after new requests have been observed.
+func (o *Observer) Changed() <-chan struct{} {
+	return o.changed
+}
+
+// Observe records a request made through the proxy.
+func (o *Observer) Observe(r *Request) *Observation {
+	o.mu.Lock()
+
+	observation := &Observation{Request: r}
+
+	switch r.Kind {
+	case KindMaster:
+		o.observeMaster(r)
+	case KindMedia:
+		observation.Track = o.observeMedia(r).Label()
+	case KindSegment:
+		o.observeSegment(observation)
+	}
+
+	o.requests = append(o.requests, observation)
+
+	if len(o.requests) > maxRequests {
+		o.requests = o.requests[len(o.requests)-maxRequests:]
+	}
+
+	o.mu.Unlock()
+
+	select {
+	case o.changed <- struct{}{}:
+	default:
+	}
+
+	return observation
+}
+
+// observeMaster remembers the variants of the master playlist so tracks can
+// be labelled.
+func (o *Observer) observeMaster(r *Request) {
+	master, err := hls.ParseMaster(r.URL, string(r.Body))
+
+	if err != nil {
+		return
+	}
+
+	o.master = r.URL
+
+	for _, v := range master.Variants {
+		o.variants[v.URL] = v
+
+		if t, ok := o.tracks[v.URL]; ok {
+			t.Variant = v
+		}
+	}
+}
+
+// observeMedia records a reload of a media playlist.
+func (o *Observer) observeMedia(r *Request) *Track {
+	t, ok := o.tracks[r.URL]
+
+	if !ok {
+		t = &Track{URL: r.URL, Variant: o.variants[r.URL]}
+		o.tracks[r.URL] = t
+	}
+
+	if !t.LastReload.IsZero() {
+		t.Interval = r.Time.Sub(t.LastReload)
+		t.Average = (t.Average*time.Duration(t.Reloads-1) + t.Interval) / time.Duration(t.Reloads)
+	}
+
+	t.Reloads++
+	t.LastReload = r.Time
+
+	if r.Status >= 200 && r.Status <= 299 {
+		t.Playlist = hls.ParseMediaPlaylist(r.URL, string(r.Body))
+	}
+
+	return t
+}
+
+// observeSegment finds the segment in the playlists the player has loaded and
+// records a switch when it comes from a different variant. Playlists that were
+// reloaded most recently are searched first, as renditions may share segments.
+func (o *Observer) observeSegment(observation *Observation) {
+	tracks := make([]*Track, 0, len(o.tracks))
+
+	for _, t := range o.tracks {
+		if t.Playlist != nil {
+			tracks = append(tracks, t)
+		}
+	}
+
+	sort.Slice(tracks, func(i, j int) bool {
+		return tracks[i].LastReload.After(tracks[j].LastReload)
+	})
+
+	for _, t := range tracks {
+
+		segments := t.Playlist.Segments
+
+		for i, s := range segments {
+			if t.Playlist.ResolveURI(s.URI) != observation.URL || !matchesRange(s, observation.Range) {
+				continue
+			}
+
+			observation.Track = t.Label()
+			observation.Segment = s
+			observation.Behind = len(segments) - 1 - i
+
+			for _, later := range segments[i:] {
+				observation.Distance += time.Duration(later.Duration * float64(time.Second))
+			}
+
+			t.LastSegment = s
+			t.LastRequest = observation.Time
+			t.Behind = observation.Behind
+			t.Distance = observation.Distance
+
+			if t.IsVariant() && o.current != t {
+				if o.current != nil {
+					observation.Switch = &Switch{Time: observation.Time, From: o.current.Label(), To: t.Label()}
+					o.switches = append(o.switches, *observation.Switch)
+
+					if len(o.switches) > maxSwitches {
+						o.switches = o.switches[len(o.switches)-maxSwitches:]
+					}
+				}
+
+				o.current = t
+			}
+
+			return
+		}
+	}
+}
+
+// Report returns a copy of what has been observed. Tracks are sorted with the
+// variants first, by bandwidth.
+func (o *Observer) Report() *Report {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	report := &Report{
+		Master:   o.master,
+		Switches: append([]Switch{}, o.switches...),
+		Requests: append([]*Observation{}, o.requests...),
+	}
+
+	if o.current != nil {
+		current := *o.current
+		report.Current = &current
+	}
+
+	for _, t := range o.tracks {
+		track := *t
+		report.Tracks = append(report.Tracks, &track)
+	}
+
+	sort.Slice(report.Tracks, func(i, j int) bool {
+		a, b := report.Tracks[i], report.Tracks[j]
+
+		if a.IsVariant() != b.IsVariant() {
+			return a.IsVariant()
+		}
+
+		if a.Variant != nil && b.Variant != nil && a.Variant.Bandwidth != b.Variant.Bandwidth {
+			return a.Variant.Bandwidth > b.Variant.Bandwidth
+		}
+
+		return a.URL < b.URL
+	})
+
+	return report
+}
+
+// matchesRange reports whether the Range header of a request is for the
+// segment. Requests without a range match segments without one.
+func matchesRange(s *hls.Segment, header string) bool {
+	if s.ByteRange == nil {
+		return true
+	}
+
+	if header == "" {
+		return false
+	}
+
+	start := strings.SplitN(strings.TrimPrefix(header, "bytes="), "-", 2)[0]
+
+	return start == strconv.FormatInt(s.ByteRange.Offset, 10)
+}
@@ -0,0 +1,494 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// kindPlaylist is used in the path of the playlist the proxy was started
+// with, whether it is a master or media playlist is decided by its contents.
+const kindPlaylist = "playlist"
+
+// uriAttribute matches the URI attribute of a tag.
+var uriAttribute = regexp.MustCompile(`URI="([^"]*)"`)
+
+// Options configure a Proxy.
+type Options struct {
+	// Faults are applied in order to every request of their kind.
+	Faults []*Fault
+
+	// Seed seeds the random numbers that decide whether a fault is applied, the
+	// same seed and requests inject the same faults.
+	Seed int64
+
+	// Client makes the requests to the origin, http.DefaultClient is used when
+	// nil.
+	Client *http.Client
+
+	// Log receives a line for every fault injected, it may be nil.
+	Log io.Writer
+
+	// Observe is called with every request once it has been answered, it may
+	// be nil.
+	Observe func(r *Request)
+}
+
+// Request is a request made through the proxy.
+type Request struct {
+	Time   time.Time
+	Kind   Kind
+	URL    string
+	Range  string
+	Status int
+	Bytes  int
+
+	// Latency is the time the origin took to respond and Duration the time
+	// until the response was written, including injected latency.
+	Latency  time.Duration
+	Duration time.Duration
+
+	// Faults are the faults injected into the response.
+	Faults []*Fault
+
+	// Body is the playlist from the origin, before it was rewritten. It is
+	// only set for playlists.
+	Body []byte
+}
+
+// playlistState is what the proxy remembers about a playlist between reloads.
+type playlistState struct {
+	current  []byte
+	previous []byte
+	jump     int
+}
+
+// Proxy forwards requests to the origin and injects faults into the
+// responses. Playlists are rewritten so that every uri in them is requested
+// through the proxy too, with the kind of request in the path as
+// /<kind>/<scheme>/<host>/<path>.
+type Proxy struct {
+	opts      Options
+	mu        sync.Mutex
+	rand      *rand.Rand
+	playlists map[string]*playlistState
+}
+
+// New creates a Proxy.
+func New(opts Options) *Proxy {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	if opts.Log == nil {
+		opts.Log = ioutil.Discard
+	}
+
+	return &Proxy{
+		opts:      opts,
+		rand:      rand.New(rand.NewSource(opts.Seed)),
+		playlists: make(map[string]*playlistState),
+	}
+}
+
+// Path returns the path of the proxy that serves the playlist at upstream.
+func Path(upstream string) (string, error) {
+	u, err := url.Parse(upstream)
+
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%s is not an http url", upstream)
+	}
+
+	return proxyPath(kindPlaylist, u), nil
+}
+
+// proxyPath returns the proxy path for a request of kind to u.
+func proxyPath(kind string, u *url.URL) string {
+	p := fmt.Sprintf("/%s/%s/%s%s", kind, u.Scheme, u.Host, u.EscapedPath())
+
+	if u.RawQuery != "" {
+		p += "?" + u.RawQuery
+	}
+
+	return p
+}
+
+// upstreamURL returns the kind and origin url of a proxy request.
+func upstreamURL(r *http.Request) (string, *url.URL, error) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/", 4)
+
+	if len(parts) < 3 {
+		return "", nil, errors.New("the path is not in the form /<kind>/<scheme>/<host>/<path>")
+	}
+
+	rawurl := fmt.Sprintf("%s://%s/", parts[1], parts[2])
+
+	if len(parts) == 4 {
+		rawurl += parts[3]
+	}
+
+	if r.URL.RawQuery != "" {
+		rawurl += "?" + r.URL.RawQuery
+	}
+
+	u, err := url.Parse(rawurl)
+
+	return parts[0], u, err
+}
+
+// ServeHTTP requests the resource from the origin and applies the faults.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	kind, upstream, err := upstreamURL(r)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+
+	resp, body, err := p.fetch(r, upstream)
+
+	latency := time.Since(start)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+
+		p.observe(&Request{
+			Time:     start,
+			Kind:     Kind(kind),
+			URL:      upstream.String(),
+			Range:    r.Header.Get("Range"),
+			Status:   http.StatusBadGateway,
+			Latency:  latency,
+			Duration: time.Since(start),
+		})
+
+		return
+	}
+
+	original := body
+
+	status := resp.StatusCode
+	contentLength := len(body)
+	isPlaylist := strings.HasPrefix(strings.TrimSpace(string(body)), "#EXTM3U")
+
+	if isPlaylist {
+		kind = string(KindMedia)
+
+		if hls.IsMasterPlaylist(string(body)) {
+			kind = string(KindMaster)
+		}
+	} else if kind == kindPlaylist {
+		kind = string(KindSegment)
+	}
+
+	faults := p.pick(Kind(kind))
+
+	var delay time.Duration
+
+	for _, f := range faults {
+		switch f.Name {
+		case FaultLatency:
+			delay += f.Latency
+		case FaultStatus:
+			status = f.Status
+			body = []byte(http.StatusText(f.Status) + "\n")
+			contentLength = len(body)
+			isPlaylist = false
+		}
+	}
+
+	if isPlaylist && status >= 200 && status <= 299 {
+		body = p.playlist(Kind(kind), resp.Request.URL, body, faults)
+		contentLength = len(body)
+	}
+
+	// A truncated body keeps the original length so the client sees the
+	// connection close early.
+	for _, f := range faults {
+		if f.Name == FaultTruncate && status >= 200 && status <= 299 {
+			body = body[:truncated(len(body), f.Fraction)]
+		}
+	}
+
+	for _, f := range faults {
+		fmt.Fprintf(p.opts.Log, "%s %s %s %s\n", time.Now().Format("15:04:05.000"), kind, f, upstream)
+	}
+
+	if !sleep(r.Context(), delay) {
+		return
+	}
+
+	for name, values := range resp.Header {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", "Connection":
+			continue
+		}
+
+		w.Header()[name] = values
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(contentLength))
+	w.WriteHeader(status)
+	w.Write(body)
+
+	req := &Request{
+		Time:     start,
+		Kind:     Kind(kind),
+		URL:      resp.Request.URL.String(),
+		Range:    r.Header.Get("Range"),
+		Status:   status,
+		Bytes:    len(body),
+		Latency:  latency,
+		Duration: time.Since(start),
+		Faults:   faults,
+	}
+
+	if kind == string(KindMaster) || kind == string(KindMedia) {
+		req.Body = original
+	}
+
+	p.observe(req)
+}
+
+// observe passes r to the Observe option.
+func (p *Proxy) observe(r *Request) {
+	if p.opts.Observe != nil {
+		p.opts.Observe(r)
+	}
+}
+
+// fetch requests upstream, passing on the range of the request.
+func (p *Proxy) fetch(r *http.Request, upstream *url.URL) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, upstream.String(), nil)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, name := range []string{"Range", "User-Agent", "If-None-Match", "If-Modified-Since"} {
+		if value := r.Header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+
+	resp, err := p.opts.Client.Do(req)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, body, nil
+}
+
+// pick decides which faults are applied to a request of kind.
+func (p *Proxy) pick(kind Kind) []*Fault {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var faults []*Fault
+
+	for _, f := range p.opts.Faults {
+		if !f.applies(kind) {
+			continue
+		}
+
+		// A number is drawn for every fault so that the sequence only depends
+		// on the seed and the requests.
+		if p.rand.Float64() < f.Probability {
+			faults = append(faults, f)
+		}
+	}
+
+	return faults
+}
+
+// playlist applies the playlist faults to body and rewrites its uris to go
+// through the proxy.
+func (p *Proxy) playlist(kind Kind, base *url.URL, body []byte, faults []*Fault) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.playlists[base.String()]
+
+	if !ok {
+		state = &playlistState{}
+		p.playlists[base.String()] = state
+	}
+
+	if string(body) != string(state.current) {
+		state.previous = state.current
+		state.current = body
+	}
+
+	raw := string(body)
+
+	for _, f := range faults {
+		switch f.Name {
+		case FaultStale:
+			if state.previous != nil {
+				raw = string(state.previous)
+			}
+		case FaultDrop:
+			raw = p.dropSegment(base, raw)
+		case FaultJump:
+			state.jump += f.Jump
+		}
+	}
+
+	lines := strings.Split(raw, "\n")
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#EXT-X-MEDIA-SEQUENCE:") && state.jump != 0:
+			sequence, _ := strconv.Atoi(strings.TrimPrefix(trimmed, "#EXT-X-MEDIA-SEQUENCE:"))
+			lines[i] = fmt.Sprintf("#EXT-X-MEDIA-SEQUENCE:%d", sequence+state.jump)
+		case strings.HasPrefix(trimmed, "#"):
+			lines[i] = uriAttribute.ReplaceAllStringFunc(trimmed, func(attr string) string {
+				uri := uriAttribute.FindStringSubmatch(attr)[1]
+				return fmt.Sprintf("URI=%q", rewrite(tagKind(kind, trimmed), base, uri))
+			})
+		default:
+			uriKind := KindSegment
+
+			if kind == KindMaster {
+				uriKind = KindMedia
+			}
+
+			lines[i] = rewrite(uriKind, base, trimmed)
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// dropSegment removes a random segment from a media playlist. Tags that apply
+// to the segments after it, such as keys, are kept.
+func (p *Proxy) dropSegment(base *url.URL, raw string) string {
+	playlist := hls.ParseMediaPlaylist(base.String(), raw)
+
+	if len(playlist.Segments) == 0 {
+		return raw
+	}
+
+	drop := p.rand.Intn(len(playlist.Segments))
+
+	lines := strings.Split(raw, "\n")
+	kept := make([]string, 0, len(lines))
+	segment := 0
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if segment == drop {
+			switch {
+			case trimmed != "" && !strings.HasPrefix(trimmed, "#"):
+				segment++
+				continue
+			case strings.HasPrefix(trimmed, "#EXTINF"),
+				strings.HasPrefix(trimmed, "#EXT-X-BYTERANGE"),
+				strings.HasPrefix(trimmed, "#EXT-X-PROGRAM-DATE-TIME"),
+				strings.HasPrefix(trimmed, "#EXT-X-GAP"):
+				continue
+			}
+		} else if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			segment++
+		}
+
+		kept = append(kept, line)
+	}
+
+	return strings.Join(kept, "\n")
+}
+
+// tagKind returns the kind of the uri in a tag of a playlist of kind.
+func tagKind(kind Kind, tag string) Kind {
+	switch {
+	case strings.HasPrefix(tag, "#EXT-X-KEY"), strings.HasPrefix(tag, "#EXT-X-SESSION-KEY"):
+		return KindKey
+	case strings.HasPrefix(tag, "#EXT-X-MEDIA"),
+		strings.HasPrefix(tag, "#EXT-X-I-FRAME-STREAM-INF"),
+		strings.HasPrefix(tag, "#EXT-X-RENDITION-REPORT"):
+		return KindMedia
+	case kind == KindMaster:
+		return KindMedia
+	default:
+		return KindSegment
+	}
+}
+
+// rewrite returns the proxy path of ref, resolved against base. Urls that are
+// not http are left alone.
+func rewrite(kind Kind, base *url.URL, ref string) string {
+	u, err := url.Parse(ref)
+
+	if err != nil {
+		return ref
+	}
+
+	u = base.ResolveReference(u)
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ref
+	}
+
+	return proxyPath(string(kind), u)
+}
+
+// sleep waits for d, it returns false if ctx was cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// truncated returns the length of a body of n bytes cut to fraction of it,
+// kept within the body whatever the fraction.
+func truncated(n int, fraction float64) int {
+	switch {
+	case !(fraction > 0):
+		return 0
+	case fraction >= 1:
+		return n
+	}
+
+	return int(float64(n) * fraction)
+}
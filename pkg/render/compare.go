@@ -0,0 +1,213 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/moore0n/hlstail/pkg/term"
+	"github.com/moore0n/hlstail/pkg/tools"
+)
+
+// CompareScreen is the full screen view of two playlists of the same stream
+// compared segment by segment.
+type CompareScreen struct {
+	termSess *term.Session
+	count    int
+}
+
+// NewCompareScreen creates a CompareScreen showing the last count media
+// sequences, or as many as fit on the screen when count is 0.
+func NewCompareScreen(termSess *term.Session, count int) *CompareScreen {
+	return &CompareScreen{
+		termSess: termSess,
+		count:    count,
+	}
+}
+
+// Start will hide the cursor and clear the screen.
+func (s *CompareScreen) Start() {
+	s.termSess.Start()
+}
+
+// End returns the terminal to its state from before hlstail started.
+func (s *CompareScreen) End() {
+	s.termSess.End()
+}
+
+// Draw prints the comparison of the latest reloads of a and b, either of
+// which may have failed.
+func (s *CompareScreen) Draw(a *hls.Update, b *hls.Update, names [2]string) {
+	width := s.termSess.GetCliWidth()
+
+	output := new(bytes.Buffer)
+
+	fmt.Fprint(output, tools.GetHeader(width, fmt.Sprintf(" %s / %s", names[0], names[1])))
+
+	for i, u := range []*hls.Update{a, b} {
+		if u.Err == nil {
+			continue
+		}
+
+		for _, line := range tools.Wrap(fmt.Sprintf("%s: %s", names[i], u.Err), width) {
+			fmt.Fprintf(output, "\033[38;5;160m%s\033[0m\r\n", line)
+		}
+	}
+
+	if a.Err != nil || b.Err != nil {
+		fmt.Fprint(output, "\r\n", tools.GetFooter(width, a.Time.UTC().Format(time.RFC3339)))
+		fmt.Fprint(output, "\r\nactions: (q)uit\r\n")
+
+		s.termSess.Draw(output.String())
+		return
+	}
+
+	c := hls.Compare(a.Playlist, b.Playlist)
+
+	fmt.Fprintf(output, "%s\r\n", leadLine(c, names))
+
+	if c.Aligned() {
+		fmt.Fprint(output, "\033[38;5;40maligned, failover should be seamless\033[0m\r\n")
+	}
+
+	for _, p := range c.Problems {
+		for _, line := range tools.Wrap(p, width) {
+			fmt.Fprintf(output, "\033[38;5;160m%s\033[0m\r\n", line)
+		}
+	}
+
+	fmt.Fprint(output, tools.GetSeparator(width, "-"))
+
+	column := (width - 14) / 3
+
+	fmt.Fprintf(output, "\033[38;5;250m%12s  %s %s %s\033[0m\r\n", "sequence",
+		paneCell(names[0], column, ""), paneCell(names[1], column, ""), "differences")
+
+	matches := c.Segments
+	count := s.count
+
+	if count == 0 {
+		// A row for each media sequence, less the footer and the actions.
+		count = s.termSess.GetCliHeight() - rows(output.String()) - 4
+	}
+
+	if count < 1 {
+		count = 1
+	}
+
+	if len(matches) > count {
+		matches = matches[len(matches)-count:]
+	}
+
+	for _, m := range matches {
+		color := "\033[38;5;250m"
+
+		switch {
+		case len(m.Differences) > 0:
+			color = "\033[38;5;226m"
+		case m.A == nil || m.B == nil:
+			color = ""
+		}
+
+		line := fmt.Sprintf("%12d  %s %s %s", m.Sequence,
+			paneCell(tools.TruncateMiddle(compareCell(m.A), column), column, ""),
+			paneCell(tools.TruncateMiddle(compareCell(m.B), column), column, ""), strings.Join(m.Differences, ", "))
+
+		fmt.Fprintf(output, "%s%s\033[0m\r\n", color, clip(line, width))
+	}
+
+	fmt.Fprint(output, "\r\n", tools.GetFooter(width, a.Time.UTC().Format(time.RFC3339)))
+
+	fmt.Fprint(output, "\r\nactions: (q)uit\r\n")
+
+	s.termSess.Draw(output.String())
+}
+
+// CompareText writes the state of two playlists after every reload, and each
+// segment that differs once.
+type CompareText struct {
+	w    io.Writer
+	last int
+}
+
+// NewCompareText creates a CompareText writing to w.
+func NewCompareText(w io.Writer) *CompareText {
+	return &CompareText{w: w, last: -1}
+}
+
+// Comparison writes the comparison of the latest reloads of a and b.
+func (t *CompareText) Comparison(a *hls.Update, b *hls.Update, names [2]string) {
+	now := a.Time.UTC().Format(time.RFC3339)
+
+	for i, u := range []*hls.Update{a, b} {
+		if u.Err != nil {
+			fmt.Fprintf(t.w, "%s error %s %s\n", now, names[i], u.Err)
+		}
+	}
+
+	if a.Err != nil || b.Err != nil {
+		return
+	}
+
+	c := hls.Compare(a.Playlist, b.Playlist)
+
+	state := "aligned"
+
+	if !c.Aligned() {
+		state = "diverged: " + strings.Join(c.Problems, "; ")
+	}
+
+	fmt.Fprintf(t.w, "%s compare %s, %s\n", now, leadLine(c, names), state)
+
+	for _, m := range c.Segments {
+		// Only segments both playlists have are compared, wait for the other.
+		if m.Sequence <= t.last || m.A == nil || m.B == nil {
+			continue
+		}
+
+		t.last = m.Sequence
+
+		if len(m.Differences) > 0 {
+			fmt.Fprintf(t.w, "%s differ %d %s\n", now, m.Sequence, strings.Join(m.Differences, ", "))
+		}
+	}
+}
+
+// leadLine says which playlist has the newer live edge and by how much.
+func leadLine(c *hls.Comparison, names [2]string) string {
+	lead, by, d := names[0], c.Lead, c.LeadTime
+
+	if c.Lead < 0 || (c.Lead == 0 && c.LeadTime < 0) {
+		lead, by, d = names[1], -c.Lead, -c.LeadTime
+	}
+
+	if by == 0 && d == 0 {
+		return "live edges match"
+	}
+
+	noun := "segments"
+
+	if by == 1 {
+		noun = "segment"
+	}
+
+	return fmt.Sprintf("%s leads by %d %s (%.1fs)", lead, by, noun, d.Seconds())
+}
+
+// compareCell describes a segment in a column of the comparison.
+func compareCell(s *hls.Segment) string {
+	if s == nil {
+		return "-"
+	}
+
+	cell := fmt.Sprintf("%.3f %s", s.Duration, s.URI)
+
+	if !s.ProgramDateTime.IsZero() {
+		cell = s.ProgramDateTime.UTC().Format("15:04:05.000") + " " + cell
+	}
+
+	return cell
+}
@@ -0,0 +1,139 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/dashboard"
+	"github.com/moore0n/hlstail/pkg/term"
+	"github.com/moore0n/hlstail/pkg/tools"
+)
+
+// DashboardScreen is the full screen view of many streams, one row each.
+type DashboardScreen struct {
+	termSess *term.Session
+}
+
+// NewDashboardScreen creates a DashboardScreen.
+func NewDashboardScreen(termSess *term.Session) *DashboardScreen {
+	return &DashboardScreen{termSess: termSess}
+}
+
+// Start will hide the cursor and clear the screen.
+func (s *DashboardScreen) Start() {
+	s.termSess.Start()
+}
+
+// End returns the terminal to its state from before hlstail started.
+func (s *DashboardScreen) End() {
+	s.termSess.End()
+}
+
+// Draw prints a row for each stream with the selected one highlighted.
+func (s *DashboardScreen) Draw(statuses []*dashboard.Status, selectedIndex int) {
+	width := s.termSess.GetCliWidth()
+	now := time.Now()
+
+	output := new(bytes.Buffer)
+
+	fmt.Fprint(output, tools.GetHeader(width, " Streams"))
+
+	counts := make(map[string]int)
+
+	for _, st := range statuses {
+		counts[st.State()]++
+	}
+
+	fmt.Fprintf(output, "%d streams, \033[38;5;40m%d ok\033[0m, \033[38;5;226m%d alerting\033[0m, \033[38;5;160m%d failing\033[0m\r\n",
+		len(statuses), counts[dashboard.StateOK], counts[dashboard.StateAlert], counts[dashboard.StateError])
+
+	fmt.Fprint(output, tools.GetSeparator(width, "-"))
+	fmt.Fprintf(output, "\033[38;5;250m%s\033[0m\r\n", clip(dashboardRow("stream", "status", "live edge", "age", "latency", "fetch", "errors", "alerts"), width))
+
+	for i, st := range statuses {
+		line := clip(dashboardLine(st, now), width)
+
+		if i == selectedIndex {
+			fmt.Fprintf(output, "\033[0;30;47m%s\033[0m\r\n", line)
+			continue
+		}
+
+		color := "\033[38;5;40m"
+
+		switch st.State() {
+		case dashboard.StateWaiting:
+			color = "\033[38;5;250m"
+		case dashboard.StateAlert:
+			color = "\033[38;5;226m"
+		case dashboard.StateError:
+			color = "\033[38;5;160m"
+		}
+
+		fmt.Fprintf(output, "%s%s\033[0m\r\n", color, line)
+	}
+
+	fmt.Fprint(output, "\r\n", tools.GetFooter(width, now.UTC().Format(time.RFC3339)))
+
+	fmt.Fprint(output, "\r\nactions: (enter)tail stream (q)uit\r\n")
+
+	s.termSess.Draw(output.String())
+}
+
+// DashboardText writes a line for every reload of a stream.
+type DashboardText struct {
+	w io.Writer
+}
+
+// NewDashboardText creates a DashboardText writing to w.
+func NewDashboardText(w io.Writer) *DashboardText {
+	return &DashboardText{w: w}
+}
+
+// Status writes the status of a stream after a reload.
+func (t *DashboardText) Status(st *dashboard.Status) {
+	fmt.Fprintf(t.w, "%s %s\n", st.Updated.UTC().Format(time.RFC3339), strings.TrimRight(dashboardLine(st, st.Updated), " "))
+}
+
+// dashboardLine describes the status of a stream as a row of the dashboard.
+func dashboardLine(st *dashboard.Status, now time.Time) string {
+	sequence := "-"
+
+	if st.Sequence >= 0 {
+		sequence = fmt.Sprint(st.Sequence)
+	}
+
+	fetch := "-"
+
+	if st.Latency > 0 {
+		fetch = fmt.Sprintf("%dms", st.Latency.Milliseconds())
+	}
+
+	alerts := make([]string, 0, len(st.Alerts))
+
+	for _, v := range st.Alerts {
+		alerts = append(alerts, v.Message)
+	}
+
+	if st.Err != nil {
+		alerts = append(alerts, st.Err.Error())
+	}
+
+	return dashboardRow(st.Stream.Name, st.State(), sequence, formatSeconds(st.Age(now)), formatSeconds(st.EdgeLatency()), fetch, fmt.Sprint(st.Errors), strings.Join(alerts, ", "))
+}
+
+// dashboardRow lays out the columns of the dashboard. Names are cut in the
+// middle as they are often urls. Latency is how far behind the live edge the
+// stream is and fetch how long the reload took.
+func dashboardRow(name, state, sequence, age, latency, fetch, errors, alerts string) string {
+	name = tools.Pad(tools.TruncateMiddle(name, 24), 24)
+
+	return fmt.Sprintf("%s %-7s %12s %7s %7s %7s %6s  %s", name, state, sequence, age, latency, fetch, errors, alerts)
+}
+
+// clip cuts line to width, marking the cut unless only spaces were lost.
+func clip(line string, width int) string {
+	return tools.Truncate(strings.TrimRight(line, " "), width)
+}
@@ -0,0 +1,72 @@
+package render
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/dashboard"
+)
+
+func TestDashboardLine(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status dashboard.Status
+		want   []string
+	}{
+		{
+			"waiting",
+			dashboard.Status{Stream: dashboard.Stream{Name: "news"}, Sequence: -1},
+			[]string{"news", "waiting", "-", "-", "-", "-", "0"},
+		},
+		{
+			"live",
+			dashboard.Status{
+				Stream:     dashboard.Stream{Name: "news"},
+				Reloads:    3,
+				Updated:    now,
+				Latency:    42 * time.Millisecond,
+				Edge:       now.Add(-6500 * time.Millisecond),
+				Sequence:   120,
+				LastChange: now.Add(-2 * time.Second),
+			},
+			[]string{"news", "ok", "120", "2.0s", "6.5s", "42ms", "0"},
+		},
+		{
+			"no program date time",
+			dashboard.Status{
+				Stream:     dashboard.Stream{Name: "news"},
+				Reloads:    1,
+				Updated:    now,
+				Latency:    7 * time.Millisecond,
+				Sequence:   5,
+				LastChange: now.Add(-time.Second),
+			},
+			[]string{"news", "ok", "5", "1.0s", "-", "7ms", "0"},
+		},
+		{
+			"failing",
+			dashboard.Status{
+				Stream:   dashboard.Stream{Name: "news"},
+				Reloads:  2,
+				Errors:   1,
+				Err:      errors.New("unexpected status 503"),
+				Updated:  now,
+				Edge:     now.Add(-10 * time.Second),
+				Sequence: 5,
+			},
+			[]string{"news", "error", "5", "-", "10.0s", "-", "1", "unexpected", "status", "503"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Fields(dashboardLine(&tt.status, now)); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("dashboardLine = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// Event names written by the JSON renderer.
+const (
+	EventReload  = "reload"
+	EventSegment = "segment"
+	EventRemoved = "removed"
+	EventError   = "error"
+	EventNotice  = "notice"
+)
+
+// Event is a single line of JSON output.
+type Event struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	URL   string    `json:"url"`
+
+	// Reload fields.
+	Status        int           `json:"status,omitempty"`
+	Headers       http.Header   `json:"headers,omitempty"`
+	LatencyMs     float64       `json:"latency_ms,omitempty"`
+	MediaSequence *int          `json:"media_sequence,omitempty"`
+	Segments      *int          `json:"segments,omitempty"`
+	Target        int           `json:"target_duration,omitempty"`
+	EndList       bool          `json:"endlist,omitempty"`
+	Added         *int          `json:"added,omitempty"`
+	Removed       *int          `json:"removed,omitempty"`
+	Segment       *SegmentEvent `json:"segment,omitempty"`
+
+	// Error and notice fields.
+	Error string `json:"error,omitempty"`
+}
+
+// SegmentEvent describes a segment that was added or removed.
+type SegmentEvent struct {
+	Sequence        int        `json:"sequence"`
+	Duration        float64    `json:"duration"`
+	URI             string     `json:"uri"`
+	ProgramDateTime *time.Time `json:"program_date_time,omitempty"`
+	Discontinuity   bool       `json:"discontinuity,omitempty"`
+	Tags            []string   `json:"tags,omitempty"`
+}
+
+// JSON writes one JSON object per event, also known as JSON Lines.
+type JSON struct {
+	enc *json.Encoder
+}
+
+// NewJSON creates a JSON renderer writing to w.
+func NewJSON(w io.Writer) *JSON {
+	return &JSON{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Start does nothing, there is no screen to prepare.
+func (j *JSON) Start() {}
+
+// Loading does nothing, only results are written.
+func (j *JSON) Loading() {}
+
+// Variants does nothing, variants are not selected interactively.
+func (j *JSON) Variants(picker *Picker, selectedIndex int, marked []int) {}
+
+// Update writes the reload followed by every removed and added segment.
+func (j *JSON) Update(u *hls.Update) {
+	if u.Err != nil {
+		e := &Event{Event: EventError, Time: u.Time, URL: u.URL, Error: u.Err.Error()}
+
+		if u.Response != nil {
+			e.Status = u.Response.StatusCode
+		}
+
+		j.enc.Encode(e)
+		return
+	}
+
+	p := u.Playlist
+	segments := len(p.Segments)
+	added := len(u.Added)
+	removed := len(u.Removed)
+
+	j.enc.Encode(&Event{
+		Event:         EventReload,
+		Time:          u.Time,
+		URL:           u.URL,
+		Status:        u.Response.StatusCode,
+		Headers:       u.Response.Header,
+		LatencyMs:     float64(u.Response.Latency) / float64(time.Millisecond),
+		MediaSequence: &p.MediaSequence,
+		Segments:      &segments,
+		Target:        p.TargetDuration,
+		EndList:       p.EndList,
+		Added:         &added,
+		Removed:       &removed,
+	})
+
+	for _, s := range u.Removed {
+		j.enc.Encode(&Event{Event: EventRemoved, Time: u.Time, URL: u.URL, Segment: newSegmentEvent(s)})
+	}
+
+	for _, s := range u.Added {
+		j.enc.Encode(&Event{Event: EventSegment, Time: u.Time, URL: u.URL, Segment: newSegmentEvent(s)})
+	}
+}
+
+// Panes writes the updates one after the other, each event carries the url
+// of its variant.
+func (j *JSON) Panes(updates []*hls.Update) {
+	for _, u := range updates {
+		j.Update(u)
+	}
+}
+
+// Paused does nothing, a JSON renderer cannot be paused.
+func (j *JSON) Paused() {}
+
+// Diff does nothing, a JSON renderer only shows what was added.
+func (j *JSON) Diff(show bool) {}
+
+// Scroll does nothing, a stream of events has no history.
+func (j *JSON) Scroll(pages int) {}
+
+// Search does nothing, a stream of events has no history.
+func (j *JSON) Search(query string) {}
+
+// Prompt does nothing, there is no input to show.
+func (j *JSON) Prompt(text string) {}
+
+// Notice writes the problem as a notice event, clearing it writes nothing.
+func (j *JSON) Notice(text string) {
+	if text == "" {
+		return
+	}
+
+	j.enc.Encode(&Event{Event: EventNotice, Time: time.Now(), Error: text})
+}
+
+// Resize does nothing, lines are written once.
+func (j *JSON) Resize() {}
+
+// End does nothing, there is no screen to restore.
+func (j *JSON) End() {}
+
+func newSegmentEvent(s *hls.Segment) *SegmentEvent {
+	e := &SegmentEvent{
+		Sequence:      s.Sequence,
+		Duration:      s.Duration,
+		URI:           s.URI,
+		Discontinuity: s.Discontinuity,
+		Tags:          s.Tags,
+	}
+
+	if !s.ProgramDateTime.IsZero() {
+		pdt := s.ProgramDateTime
+		e.ProgramDateTime = &pdt
+	}
+
+	return e
+}
@@ -0,0 +1,181 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/proxy"
+	"github.com/moore0n/hlstail/pkg/term"
+	"github.com/moore0n/hlstail/pkg/tools"
+)
+
+// observeSwitches is the number of ABR switches shown.
+const observeSwitches = 5
+
+// ObserveScreen is the full screen view of what a player fetches through an
+// observing proxy.
+type ObserveScreen struct {
+	termSess *term.Session
+	count    int
+}
+
+// NewObserveScreen creates an ObserveScreen showing the last count requests.
+func NewObserveScreen(termSess *term.Session, count int) *ObserveScreen {
+	return &ObserveScreen{
+		termSess: termSess,
+		count:    count,
+	}
+}
+
+// Start will hide the cursor and clear the screen.
+func (s *ObserveScreen) Start() {
+	s.termSess.Start()
+}
+
+// End returns the terminal to its state from before hlstail started.
+func (s *ObserveScreen) End() {
+	s.termSess.End()
+}
+
+// Draw prints the report.
+func (s *ObserveScreen) Draw(r *proxy.Report, url string) {
+	width := s.termSess.GetCliWidth()
+
+	output := new(bytes.Buffer)
+
+	fmt.Fprint(output, tools.GetHeader(width, " Player Requests"))
+
+	if r.Current == nil {
+		fmt.Fprintf(output, "waiting for a player to request %s\r\n", url)
+	} else {
+		fmt.Fprintf(output, "playing \033[38;5;40m%s\033[0m, %d segments (%s) behind live\r\n",
+			r.Current.Label(), r.Current.Behind, formatSeconds(r.Current.Distance))
+	}
+
+	if len(r.Tracks) > 0 {
+		fmt.Fprint(output, tools.GetSeparator(width, "-"))
+		fmt.Fprintf(output, "\033[38;5;250m%-28s %7s %7s %7s %12s %12s %14s\033[0m\r\n",
+			"playlist", "reloads", "every", "avg", "live edge", "requested", "behind")
+
+		for _, t := range r.Tracks {
+			edge, requested, behind := "-", "-", "-"
+
+			if t.Playlist != nil {
+				if last := t.Playlist.LastSegment(); last != nil {
+					edge = fmt.Sprint(last.Sequence)
+				}
+			}
+
+			if t.LastSegment != nil {
+				requested = fmt.Sprint(t.LastSegment.Sequence)
+				behind = fmt.Sprintf("%d / %s", t.Behind, formatSeconds(t.Distance))
+			}
+
+			fmt.Fprintf(output, "%s %7d %7s %7s %12s %12s %14s\r\n",
+				tools.Pad(tools.Truncate(t.Label(), 28), 28), t.Reloads, formatSeconds(t.Interval), formatSeconds(t.Average), edge, requested, behind)
+		}
+	}
+
+	if len(r.Switches) > 0 {
+		fmt.Fprint(output, tools.GetSeparator(width, "-"))
+
+		switches := r.Switches
+
+		if len(switches) > observeSwitches {
+			switches = switches[len(switches)-observeSwitches:]
+		}
+
+		for _, sw := range switches {
+			fmt.Fprintf(output, "%s switch \033[38;5;226m%s -> %s\033[0m\r\n", sw.Time.Format("15:04:05.000"), sw.From, sw.To)
+		}
+	}
+
+	fmt.Fprint(output, tools.GetSeparator(width, "-"))
+
+	requests := r.Requests
+
+	if len(requests) > s.count {
+		requests = requests[len(requests)-s.count:]
+	}
+
+	for _, o := range requests {
+		line := clip(observationLine(o), width)
+
+		color := "\033[38;5;250m"
+
+		switch {
+		case o.Status < 200 || o.Status > 299:
+			color = "\033[38;5;160m"
+		case len(o.Faults) > 0:
+			color = "\033[38;5;226m"
+		case o.Segment != nil:
+			color = "\033[38;5;40m"
+		}
+
+		fmt.Fprintf(output, "%s%s\033[0m\r\n", color, line)
+	}
+
+	fmt.Fprint(output, "\r\n", tools.GetFooter(width, time.Now().UTC().Format(time.RFC3339)))
+
+	fmt.Fprint(output, "\r\nactions: (q)uit\r\n")
+
+	s.termSess.Draw(output.String())
+}
+
+// ObserveText writes a line for every request a player makes.
+type ObserveText struct {
+	w io.Writer
+}
+
+// NewObserveText creates an ObserveText writing to w.
+func NewObserveText(w io.Writer) *ObserveText {
+	return &ObserveText{w: w}
+}
+
+// Observation writes the request.
+func (t *ObserveText) Observation(o *proxy.Observation) {
+	fmt.Fprintln(t.w, observationLine(o))
+}
+
+// observationLine describes a request on a single line.
+func observationLine(o *proxy.Observation) string {
+	fields := []string{
+		o.Time.Format("15:04:05.000"),
+		fmt.Sprintf("%-7s", o.Kind),
+		fmt.Sprint(o.Status),
+		fmt.Sprintf("%6dms", o.Duration.Milliseconds()),
+		fmt.Sprintf("%9dB", o.Bytes),
+	}
+
+	if o.Track != "" {
+		fields = append(fields, o.Track)
+	}
+
+	if o.Segment != nil {
+		fields = append(fields, fmt.Sprintf("#%d behind %d (%s)", o.Segment.Sequence, o.Behind, formatSeconds(o.Distance)))
+	}
+
+	if o.Switch != nil {
+		fields = append(fields, fmt.Sprintf("switch from %s", o.Switch.From))
+	}
+
+	for _, f := range o.Faults {
+		fields = append(fields, "fault "+f.String())
+	}
+
+	fields = append(fields, o.URL)
+
+	return strings.Join(fields, " ")
+}
+
+// formatSeconds formats d in seconds with one decimal place.
+func formatSeconds(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
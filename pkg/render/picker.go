@@ -0,0 +1,398 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/moore0n/hlstail/pkg/codecs"
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/moore0n/hlstail/pkg/tools"
+)
+
+// pickerColumn is a column of the variant picker.
+type pickerColumn struct {
+	name  string
+	value func(i int, v *hls.Variant) string
+
+	// key orders the column numerically, columns without one are ordered
+	// by their text.
+	key func(i int, v *hls.Variant) float64
+}
+
+// pickerColumns are the columns of the variant picker in the order shown.
+var pickerColumns = []pickerColumn{
+	{
+		name:  "#",
+		value: func(i int, v *hls.Variant) string { return strconv.Itoa(i + 1) },
+		key:   func(i int, v *hls.Variant) float64 { return float64(i) },
+	},
+	{
+		name:  "NAME",
+		value: renditionName,
+	},
+	{
+		name:  "BANDWIDTH",
+		value: func(i int, v *hls.Variant) string { return v.Attributes["BANDWIDTH"] },
+		key:   func(i int, v *hls.Variant) float64 { return number(v.Attributes["BANDWIDTH"]) },
+	},
+	{
+		name:  "AVG-BW",
+		value: func(i int, v *hls.Variant) string { return v.Attributes["AVERAGE-BANDWIDTH"] },
+		key:   func(i int, v *hls.Variant) float64 { return number(v.Attributes["AVERAGE-BANDWIDTH"]) },
+	},
+	{
+		name:  "RESOLUTION",
+		value: func(i int, v *hls.Variant) string { return v.Attributes["RESOLUTION"] },
+		key:   func(i int, v *hls.Variant) float64 { return pixels(v.Attributes["RESOLUTION"]) },
+	},
+	{
+		name:  "FPS",
+		value: func(i int, v *hls.Variant) string { return v.Attributes["FRAME-RATE"] },
+		key:   func(i int, v *hls.Variant) float64 { return number(v.Attributes["FRAME-RATE"]) },
+	},
+	{
+		name:  "CODECS",
+		value: func(i int, v *hls.Variant) string { return codecs.Describe(v.Attributes["CODECS"]) },
+	},
+	{
+		name:  "RANGE",
+		value: func(i int, v *hls.Variant) string { return v.Attributes["VIDEO-RANGE"] },
+	},
+	{
+		name:  "HDCP",
+		value: func(i int, v *hls.Variant) string { return v.Attributes["HDCP-LEVEL"] },
+	},
+	{
+		name:  "GROUPS",
+		value: groups,
+	},
+	{
+		name:  "URL",
+		value: func(i int, v *hls.Variant) string { return v.URL },
+	},
+}
+
+// Picker orders and filters the variants of a master playlist as a table to
+// pick from. Variants are always referred to by their index in the master.
+type Picker struct {
+	variants   []*hls.Variant
+	cells      [][]string
+	sortColumn int
+	descending bool
+	filter     string
+	rows       []int
+}
+
+// NewPicker creates a Picker listing variants in the order of the master.
+func NewPicker(variants []*hls.Variant) *Picker {
+	p := &Picker{}
+	p.SetVariants(variants)
+
+	return p
+}
+
+// SetVariants replaces the variants, keeping the order and filter.
+func (p *Picker) SetVariants(variants []*hls.Variant) {
+	p.variants = variants
+	p.cells = make([][]string, len(variants))
+
+	for i, v := range variants {
+		p.cells[i] = make([]string, len(pickerColumns))
+
+		for c, column := range pickerColumns {
+			p.cells[i][c] = column.value(i, v)
+		}
+	}
+
+	p.update()
+}
+
+// Len returns the number of variants, whether they are shown or not.
+func (p *Picker) Len() int {
+	return len(p.variants)
+}
+
+// Sort orders the rows by the next column, going back to the order of the
+// master after the last.
+func (p *Picker) Sort() {
+	p.sortColumn = (p.sortColumn + 1) % len(pickerColumns)
+	p.descending = false
+	p.update()
+}
+
+// Reverse flips the order of the rows.
+func (p *Picker) Reverse() {
+	p.descending = !p.descending
+	p.update()
+}
+
+// Filter returns the text rows are filtered by.
+func (p *Picker) Filter() string {
+	return p.filter
+}
+
+// SetFilter shows only the variants that contain every word of filter in one
+// of their columns, or the codecs as written in the master, ignoring case.
+func (p *Picker) SetFilter(filter string) {
+	p.filter = filter
+	p.update()
+}
+
+// Rows returns the indexes of the variants shown, in order.
+func (p *Picker) Rows() []int {
+	return p.rows
+}
+
+// Visible reports whether the variant at index is shown.
+func (p *Picker) Visible(index int) bool {
+	return p.position(index) >= 0
+}
+
+// Move returns the index of the variant by rows down from the one at index,
+// negative is up, stopping at the first and last row. A variant that is not
+// shown moves to the first row.
+func (p *Picker) Move(index int, by int) int {
+	if len(p.rows) == 0 {
+		return index
+	}
+
+	i := p.position(index) + by
+
+	if p.position(index) < 0 {
+		i = 0
+	}
+
+	if i < 0 {
+		i = 0
+	}
+
+	if i >= len(p.rows) {
+		i = len(p.rows) - 1
+	}
+
+	return p.rows[i]
+}
+
+// Problems lists what is wrong with the CODECS of each variant that has them.
+// The attribute is optional, and renditions have none of their own.
+func (p *Picker) Problems() []string {
+	var problems []string
+
+	for i, v := range p.variants {
+		value, ok := v.Attributes["CODECS"]
+
+		if v.Type != "" || !ok {
+			continue
+		}
+
+		for _, err := range codecs.Validate(value) {
+			problems = append(problems, fmt.Sprintf("variant %d codecs: %s", i+1, err))
+		}
+	}
+
+	return problems
+}
+
+// position returns the row of the variant at index, or -1 if it is not
+// shown.
+func (p *Picker) position(index int) int {
+	for i, row := range p.rows {
+		if row == index {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// update filters and orders the rows.
+func (p *Picker) update() {
+	words := strings.Fields(strings.ToLower(p.filter))
+	p.rows = p.rows[:0]
+
+	for i, v := range p.variants {
+		text := strings.ToLower(strings.Join(p.cells[i], " ") + " " + v.Attributes["CODECS"])
+		matches := true
+
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			p.rows = append(p.rows, i)
+		}
+	}
+
+	column := pickerColumns[p.sortColumn]
+
+	less := func(a, b int) bool {
+		if column.key != nil {
+			return column.key(a, p.variants[a]) < column.key(b, p.variants[b])
+		}
+
+		return strings.ToLower(p.cells[a][p.sortColumn]) < strings.ToLower(p.cells[b][p.sortColumn])
+	}
+
+	sort.SliceStable(p.rows, func(i, j int) bool {
+		if p.descending {
+			return less(p.rows[j], p.rows[i])
+		}
+
+		return less(p.rows[i], p.rows[j])
+	})
+}
+
+// table lays out the header and the shown rows in columns, leaving out the
+// columns no variant has a value for. The url takes whatever width is left
+// and is cut in the middle to fit.
+func (p *Picker) table(width int) (string, []string) {
+	var shown []int
+
+	for c := range pickerColumns {
+		for i := range p.variants {
+			if p.cells[i][c] != "" {
+				shown = append(shown, c)
+				break
+			}
+		}
+	}
+
+	widths := make([]int, len(pickerColumns))
+	used := 0
+
+	for _, c := range shown {
+		widths[c] = tools.Width(p.header(c))
+
+		for i := range p.variants {
+			if w := tools.Width(p.cells[i][c]); w > widths[c] {
+				widths[c] = w
+			}
+		}
+
+		if pickerColumns[c].name != "URL" {
+			used += widths[c] + 2
+		}
+	}
+
+	// The url column starts after a space for the mark.
+	urlWidth := width - used - 1
+
+	if urlWidth < 10 {
+		urlWidth = 10
+	}
+
+	line := func(cell func(c int) string) string {
+		fields := make([]string, 0, len(shown))
+
+		for _, c := range shown {
+			text := cell(c)
+
+			switch {
+			case pickerColumns[c].name == "URL":
+				fields = append(fields, tools.TruncateMiddle(text, urlWidth))
+			case pickerColumns[c].key != nil:
+				fields = append(fields, tools.PadLeft(text, widths[c]))
+			default:
+				fields = append(fields, tools.Pad(text, widths[c]))
+			}
+		}
+
+		return strings.TrimRight(strings.Join(fields, "  "), " ")
+	}
+
+	header := line(p.header)
+	rows := make([]string, len(p.rows))
+
+	for r, i := range p.rows {
+		rows[r] = line(func(c int) string { return p.cells[i][c] })
+	}
+
+	return header, rows
+}
+
+// header names column c, marking the one the rows are sorted by.
+func (p *Picker) header(c int) string {
+	name := pickerColumns[c].name
+
+	if c != p.sortColumn || c == 0 && !p.descending {
+		return name
+	}
+
+	if p.descending {
+		return name + "↓"
+	}
+
+	return name + "↑"
+}
+
+// SortName describes the order of the rows.
+func (p *Picker) SortName() string {
+	if p.sortColumn == 0 && !p.descending {
+		return "playlist order"
+	}
+
+	order := "ascending"
+
+	if p.descending {
+		order = "descending"
+	}
+
+	return fmt.Sprintf("%s %s", strings.ToLower(pickerColumns[p.sortColumn].name), order)
+}
+
+// renditionName names a rendition from an EXT-X-MEDIA tag by its type, name
+// and language.
+func renditionName(i int, v *hls.Variant) string {
+	if v.Type == "" {
+		return ""
+	}
+
+	name := strings.ToLower(v.Type) + " " + v.Attributes["NAME"]
+
+	if language := v.Attributes["LANGUAGE"]; language != "" {
+		name += " (" + language + ")"
+	}
+
+	return name
+}
+
+// groups lists the rendition groups a variant uses, or the group a
+// rendition belongs to.
+func groups(i int, v *hls.Variant) string {
+	if v.Type != "" {
+		return v.Attributes["GROUP-ID"]
+	}
+
+	var refs []string
+
+	for _, attr := range []string{"AUDIO", "VIDEO", "SUBTITLES", "CLOSED-CAPTIONS"} {
+		if group, ok := v.Attributes[attr]; ok && group != "NONE" {
+			refs = append(refs, strings.ToLower(attr)+"="+group)
+		}
+	}
+
+	return strings.Join(refs, " ")
+}
+
+// number parses a numeric attribute, 0 if it is missing.
+func number(value string) float64 {
+	n, _ := strconv.ParseFloat(value, 64)
+
+	return n
+}
+
+// pixels orders resolutions by their number of pixels.
+func pixels(resolution string) float64 {
+	parts := strings.SplitN(resolution, "x", 2)
+
+	if len(parts) != 2 {
+		return 0
+	}
+
+	return number(parts[0]) * number(parts[1])
+}
@@ -0,0 +1,51 @@
+package render
+
+import "github.com/moore0n/hlstail/pkg/hls"
+
+// Renderer presents the state of a tail session to the user.
+type Renderer interface {
+	// Start prepares the output before anything is drawn.
+	Start()
+
+	// Loading shows that a request is in flight.
+	Loading()
+
+	// Variants shows the variants of the master playlist, as ordered and
+	// filtered by picker, with one selected and any marked to be tailed
+	// together.
+	Variants(picker *Picker, selectedIndex int, marked []int)
+
+	// Update shows the result of reloading the tailed variant.
+	Update(u *hls.Update)
+
+	// Panes shows the reloads of several variants tailed side by side.
+	Panes(updates []*hls.Update)
+
+	// Paused shows that updates are paused.
+	Paused()
+
+	// Diff switches between showing the segments and a diff of the playlist
+	// against the previous reload.
+	Diff(show bool)
+
+	// Scroll moves through the history of segments seen by pages, negative
+	// is older. Scrolling past the newest segment returns to the live view.
+	Scroll(pages int)
+
+	// Search shows the newest segment in the history that matches query and
+	// is older than the last match.
+	Search(query string)
+
+	// Prompt shows the search being typed, an empty prompt hides it.
+	Prompt(text string)
+
+	// Notice shows a problem that is not part of a reload, such as the
+	// master playlist failing to refresh. An empty text clears it.
+	Notice(text string)
+
+	// Resize draws the screen again once the terminal has changed size.
+	Resize()
+
+	// End restores the output once the session is over.
+	End()
+}
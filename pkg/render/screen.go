@@ -0,0 +1,630 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/moore0n/hlstail/pkg/term"
+	"github.com/moore0n/hlstail/pkg/tools"
+)
+
+// diffContext is the number of unchanged lines shown around each change in
+// the diff view.
+const diffContext = 2
+
+// Screen is the full screen terminal renderer, every call draws the whole
+// screen and the session writes out the lines that changed.
+type Screen struct {
+	termSess *term.Session
+	count    int
+
+	// page is the number of segments on the screen, count or the number that
+	// fit when count is 0.
+	page int
+
+	update *hls.Update
+	panes  []*hls.Update
+	paused bool
+	diff   bool
+
+	// picker is set while the variants are shown, with the one selected and
+	// those marked.
+	picker   *Picker
+	selected int
+	marked   []int
+
+	// history holds the segments of the variant at historyURL. While
+	// scrolling anchor is the segment at the bottom of the screen, it is nil
+	// in the live view.
+	history    *hls.History
+	historyMax int
+	historyURL string
+	anchor     *hls.Segment
+	match      *hls.Segment
+	prompt     string
+	notice     string
+}
+
+// NewScreen creates a Screen showing the last count segments, or as many as
+// fit on the screen when count is 0, and keeping a history of up to history
+// segments to scroll through.
+func NewScreen(termSess *term.Session, count int, history int) *Screen {
+	return &Screen{
+		termSess:   termSess,
+		count:      count,
+		page:       count,
+		history:    hls.NewHistory(history),
+		historyMax: history,
+	}
+}
+
+// Start will hide the cursor and clear the screen.
+func (s *Screen) Start() {
+	s.termSess.Start()
+}
+
+// Loading takes over the screen with loading feedback.
+func (s *Screen) Loading() {
+	s.termSess.Draw(tools.GetLoading(s.termSess.GetCliWidth()))
+}
+
+// Variants prints the variant selection screen.
+func (s *Screen) Variants(picker *Picker, selectedIndex int, marked []int) {
+	s.picker = picker
+	s.selected = selectedIndex
+	s.marked = marked
+	s.update = nil
+	s.panes = nil
+	s.draw()
+}
+
+// maxCodecProblems is the most lines of codec problems shown above the
+// variants.
+const maxCodecProblems = 3
+
+// drawVariants prints the variants as a table, scrolled to keep the selected
+// one on the screen.
+func (s *Screen) drawVariants() {
+	width := s.termSess.GetCliWidth()
+	p := s.picker
+
+	output := new(bytes.Buffer)
+
+	fmt.Fprint(output, tools.GetHeader(width, " Select a variant"))
+
+	summary := fmt.Sprintf("%d of %d variants, %s", len(p.Rows()), p.Len(), p.SortName())
+
+	if p.Filter() != "" {
+		summary += fmt.Sprintf(", filtered by %q", p.Filter())
+	}
+
+	fmt.Fprintf(output, "\033[38;5;250m%s\033[0m\r\n", clip(summary, width))
+
+	// Bad codecs are listed up to a few lines, leaving the room to the
+	// variants.
+	problems := p.Problems()
+
+	for i, problem := range problems {
+		if i == maxCodecProblems-1 && len(problems) > maxCodecProblems {
+			fmt.Fprintf(output, "\033[38;5;226m... and %d more codec problems\033[0m\r\n", len(problems)-i)
+			break
+		}
+
+		fmt.Fprintf(output, "\033[38;5;226m%s\033[0m\r\n", clip(problem, width))
+	}
+
+	fmt.Fprint(output, tools.GetSeparator(width, "-"))
+
+	header, lines := p.table(width)
+
+	fmt.Fprintf(output, "\033[38;5;250m %s\033[0m\r\n", clip(header, width-1))
+
+	// The footer and the actions take four rows.
+	page := s.termSess.GetCliHeight() - rows(output.String()) - 4
+
+	if page < 1 {
+		page = 1
+	}
+
+	start := 0
+
+	for r, i := range p.Rows() {
+		if i == s.selected && r >= page {
+			start = r - page + 1
+		}
+	}
+
+	for r := start; r < len(lines) && r < start+page; r++ {
+		i := p.Rows()[r]
+		mark := " "
+
+		for _, m := range s.marked {
+			if m == i {
+				mark = "*"
+			}
+		}
+
+		line := mark + clip(lines[r], width-1)
+
+		if i == s.selected {
+			fmt.Fprintf(output, "\033[0;30;47m%s\033[0m\r\n", tools.Pad(line, width))
+			continue
+		}
+
+		fmt.Fprintf(output, "%s\r\n", line)
+	}
+
+	if len(lines) == 0 {
+		fmt.Fprint(output, "no variants match the filter\r\n")
+	}
+
+	fmt.Fprint(output, "\r\n", tools.GetFooter(width, ""))
+
+	switch {
+	case s.prompt != "":
+		fmt.Fprintf(output, "\r\nfilter: %s\r\n", s.prompt)
+	case s.notice != "":
+		fmt.Fprintf(output, "\r\n\033[38;5;226m%s\033[0m\r\n", clip(s.notice, width))
+	default:
+		fmt.Fprint(output, "\r\nactions: (enter)select variant (space)mark (/)filter (s)ort (S)reverse (0-9)jump (q)uit (r)efresh\r\n")
+	}
+
+	s.termSess.Draw(output.String())
+}
+
+// Update prints the last n segments of the variant.
+func (s *Screen) Update(u *hls.Update) {
+	s.update = u
+	s.panes = nil
+	s.picker = nil
+	s.paused = false
+
+	// A different variant starts a new history.
+	if u.URL != s.historyURL {
+		s.history = hls.NewHistory(s.historyMax)
+		s.historyURL = u.URL
+		s.anchor = nil
+		s.match = nil
+	}
+
+	s.history.Add(u)
+	s.draw()
+}
+
+// Panes prints the last n segments of each variant side by side.
+func (s *Screen) Panes(updates []*hls.Update) {
+	s.update = nil
+	s.panes = updates
+	s.picker = nil
+	s.paused = false
+	s.draw()
+}
+
+// Paused reprints the last update with the footer marked as paused.
+func (s *Screen) Paused() {
+	s.paused = true
+	s.draw()
+}
+
+// Diff switches between the segment list and a diff of the playlist against
+// the previous reload.
+func (s *Screen) Diff(show bool) {
+	s.diff = show
+	s.draw()
+}
+
+// Scroll moves the segment list through the history by pages.
+func (s *Screen) Scroll(pages int) {
+	segments := s.history.Segments()
+
+	if len(segments) == 0 {
+		return
+	}
+
+	end := s.historyEnd() + pages*s.page
+
+	switch {
+	case end >= len(segments)-1:
+		// Back to the live view, the next search starts from the newest.
+		s.anchor = nil
+		s.match = nil
+	case end < s.page-1 && len(segments) >= s.page:
+		s.anchor = segments[s.page-1]
+	case end < 0:
+		s.anchor = segments[0]
+	default:
+		s.anchor = segments[end]
+	}
+
+	s.notice = ""
+	s.draw()
+}
+
+// Search scrolls to the newest segment matching query that is older than the
+// last match, or tells the user there is none.
+func (s *Screen) Search(query string) {
+	before := s.history.Len()
+
+	if s.match != nil {
+		if i := s.history.Index(s.match); i >= 0 {
+			before = i
+		}
+	}
+
+	i := s.history.Search(query, before)
+
+	if i < 0 {
+		s.notice = fmt.Sprintf("no older segment matches %q", query)
+		s.draw()
+		return
+	}
+
+	s.match = s.history.Segments()[i]
+	s.anchor = s.match
+	s.notice = ""
+	s.draw()
+}
+
+// Prompt shows the search being typed.
+func (s *Screen) Prompt(text string) {
+	s.prompt = text
+
+	if text != "" {
+		s.notice = ""
+	}
+
+	s.draw()
+}
+
+// Notice shows text in place of the actions until it is cleared or the user
+// scrolls or searches.
+func (s *Screen) Notice(text string) {
+	s.notice = text
+	s.draw()
+}
+
+// Resize draws the current view again at the new size.
+func (s *Screen) Resize() {
+	s.draw()
+}
+
+// historyEnd returns the position in the history of the segment at the
+// bottom of the screen.
+func (s *Screen) historyEnd() int {
+	if s.anchor != nil {
+		if i := s.history.Index(s.anchor); i >= 0 {
+			return i
+		}
+	}
+
+	return s.history.Len() - 1
+}
+
+// historyWindow returns the part of the history to show, a page ending at
+// the anchor or the first page when the anchor is near the start.
+func (s *Screen) historyWindow() (int, int) {
+	end := s.historyEnd() + 1
+
+	if end < s.page {
+		end = s.page
+	}
+
+	if end > s.history.Len() {
+		end = s.history.Len()
+	}
+
+	start := end - s.page
+
+	if start < 0 {
+		start = 0
+	}
+
+	return start, end
+}
+
+// getHistoryToPrint colors the segments of the history window, the last
+// search match is cyan.
+func (s *Screen) getHistoryToPrint() string {
+	segments := s.history.Segments()
+	start, end := s.historyWindow()
+
+	output := new(bytes.Buffer)
+
+	for i := start; i < end; i++ {
+		color := ""
+
+		if segments[i] == s.match {
+			color = "\033[38;5;44m"
+		} else if i%2 == 0 {
+			// Gray
+			color = "\033[38;5;250m"
+		}
+
+		fmt.Fprintf(output, "\r\n%s%s\033[0m\r\n", color, strings.Join(segments[i].Lines(), "\r\n"))
+	}
+
+	return output.String()
+}
+
+// End returns the terminal to its state from before hlstail started.
+func (s *Screen) End() {
+	s.termSess.End()
+}
+
+// draw prints the last update in the current view.
+func (s *Screen) draw() {
+	if s.picker != nil {
+		s.drawVariants()
+		return
+	}
+
+	if len(s.panes) > 0 {
+		s.drawPanes()
+		return
+	}
+
+	u := s.update
+
+	if u == nil {
+		return
+	}
+
+	width := s.termSess.GetCliWidth()
+
+	output := new(bytes.Buffer)
+
+	if s.diff {
+		fmt.Fprint(output, tools.GetHeader(width, " Playlist Diff"))
+	} else {
+		fmt.Fprint(output, tools.GetHeader(width, " Segment Data"))
+	}
+
+	if u.Err != nil {
+		fmt.Fprint(output, strings.Join(tools.Wrap(u.Err.Error(), width), "\r\n"))
+	} else if s.diff {
+		fmt.Fprint(output, getDiffToPrint(u))
+	} else {
+		fmt.Fprint(output, u.GetHeaderTagsToPrint())
+		fmt.Fprint(output, tools.GetSeparator(width, "-"))
+
+		// The footer and the actions take four rows.
+		s.fit(s.termSess.GetCliHeight()-rows(output.String())-4, u.Playlist.Segments)
+
+		if s.anchor != nil {
+			fmt.Fprint(output, s.getHistoryToPrint())
+		} else {
+			fmt.Fprint(output, u.GetSegmentsToPrint(s.page))
+		}
+	}
+
+	footer := u.Time.UTC().Format(time.RFC3339)
+
+	if s.paused {
+		footer = fmt.Sprintf("PAUSED @%s", footer)
+	}
+
+	if s.anchor != nil && !s.diff {
+		start, end := s.historyWindow()
+		footer = fmt.Sprintf("HISTORY %d-%d of %d @%s", start+1, end, s.history.Len(), footer)
+	}
+
+	fmt.Fprint(output, "\r\n", tools.GetFooter(width, footer))
+
+	switch {
+	case s.prompt != "":
+		fmt.Fprintf(output, "\r\nsearch: %s\r\n", s.prompt)
+	case s.notice != "":
+		fmt.Fprintf(output, "\r\n\033[38;5;226m%s\033[0m\r\n", clip(s.notice, width))
+	default:
+		fmt.Fprint(output, "\r\nactions: (q)uit (p)ause (r)esume (n)ext (d)iff (c)hange variant (pgup/pgdn)scroll (/)search\r\n")
+	}
+
+	s.termSess.Draw(output.String())
+}
+
+// drawPanes prints the last updates of the variants in columns. Rows line up
+// by media sequence so a variant that is behind the others shows gaps.
+func (s *Screen) drawPanes() {
+	s.termSess.Draw(s.panesFrame(s.termSess.GetCliWidth(), s.termSess.GetCliHeight()))
+}
+
+// panesFrame returns the panes view for a screen of width columns and height
+// rows. A screen too narrow for the columns cuts them off on the right.
+func (s *Screen) panesFrame(width int, height int) string {
+	columns := len(s.panes)
+	paneWidth := (width - (columns-1)*len(paneSeparator)) / columns
+
+	if paneWidth < 1 {
+		paneWidth = 1
+	}
+
+	output := new(bytes.Buffer)
+
+	fmt.Fprint(output, tools.GetHeader(width, " Segment Data"))
+
+	// The newest segment across all the panes is the bottom row.
+	last := -1
+
+	for _, u := range s.panes {
+		if u.Err == nil && u.Playlist != nil {
+			if segment := u.Playlist.LastSegment(); segment != nil && segment.Sequence > last {
+				last = segment.Sequence
+			}
+		}
+	}
+
+	titles := make([]string, columns)
+	status := make([]string, columns)
+
+	for i, u := range s.panes {
+		titles[i] = paneCell(tools.TruncateMiddle(u.URL, paneWidth), paneWidth, "\033[38;5;44m")
+
+		if u.Variant != nil {
+			titles[i] = paneCell(u.Variant.Label(), paneWidth, "\033[38;5;44m")
+		}
+
+		switch {
+		case u.Err != nil:
+			status[i] = paneCell(u.Err.Error(), paneWidth, "\033[38;5;160m")
+		case u.Playlist.LastSegment() == nil:
+			status[i] = paneCell("no segments", paneWidth, "\033[38;5;250m")
+		default:
+			sequence := u.Playlist.LastSegment().Sequence
+			status[i] = paneCell(fmt.Sprintf("sequence %d, %d behind", sequence, last-sequence), paneWidth, "\033[38;5;250m")
+		}
+	}
+
+	fmt.Fprint(output, paneRow(titles, width))
+	fmt.Fprint(output, paneRow(status, width))
+	fmt.Fprint(output, tools.GetSeparator(width, "-"))
+
+	count := s.count
+
+	if count == 0 {
+		// A row for each media sequence, less the footer and the actions.
+		count = height - rows(output.String()) - 4
+	}
+
+	for sequence := last - count + 1; last >= 0 && sequence <= last; sequence++ {
+		if sequence < 0 {
+			continue
+		}
+
+		cells := make([]string, columns)
+
+		for i, u := range s.panes {
+			cells[i] = paneCell("-", paneWidth, "\033[38;5;250m")
+
+			if u.Err != nil || u.Playlist == nil {
+				continue
+			}
+
+			for _, segment := range u.Playlist.Segments {
+				if segment.Sequence != sequence {
+					continue
+				}
+
+				color := ""
+
+				if u.IsAdded(segment) {
+					color = "\033[38;5;40m"
+				}
+
+				cells[i] = paneCell(fmt.Sprintf("%d %.3f %s", segment.Sequence, segment.Duration, segment.URI), paneWidth, color)
+			}
+		}
+
+		fmt.Fprint(output, paneRow(cells, width))
+	}
+
+	footer := s.panes[0].Time.UTC().Format(time.RFC3339)
+
+	if s.paused {
+		footer = fmt.Sprintf("PAUSED @%s", footer)
+	}
+
+	fmt.Fprint(output, "\r\n", tools.GetFooter(width, footer))
+
+	if s.notice != "" {
+		fmt.Fprintf(output, "\r\n\033[38;5;226m%s\033[0m\r\n", clip(s.notice, width))
+	} else {
+		fmt.Fprint(output, "\r\nactions: (q)uit (p)ause (r)esume (n)ext (c)hange variant\r\n")
+	}
+
+	return output.String()
+}
+
+// fit sets the page to count, or when count is 0 to the number of segments
+// that fit in the rows left on the screen. Each segment takes a blank row and
+// a row per line, the longest of segments decides the size.
+func (s *Screen) fit(rows int, segments []*hls.Segment) {
+	if s.count > 0 {
+		s.page = s.count
+		return
+	}
+
+	size := 2
+
+	for _, segment := range segments {
+		if n := 1 + len(segment.Lines()); n > size {
+			size = n
+		}
+	}
+
+	s.page = rows / size
+
+	if s.page < 1 {
+		s.page = 1
+	}
+}
+
+// rows returns the number of rows text takes on the screen.
+func rows(text string) int {
+	return strings.Count(text, "\r\n")
+}
+
+// paneSeparator is printed between the columns of the panes view.
+const paneSeparator = " | "
+
+// paneRow joins the cells of a row of the panes view, cut to width.
+func paneRow(cells []string, width int) string {
+	return tools.Truncate(strings.Join(cells, paneSeparator), width) + "\r\n"
+}
+
+// paneCell cuts or pads text to the width of a pane and colors it.
+func paneCell(text string, width int, color string) string {
+	text = tools.Pad(tools.Truncate(text, width), width)
+
+	if color == "" {
+		return text
+	}
+
+	return color + text + "\033[0m"
+}
+
+// getDiffToPrint colors the diff between the previous and current playlist.
+// Added segment lines are green, removed ones red and changed playlist tags
+// yellow.
+func getDiffToPrint(u *hls.Update) string {
+	var previous []string
+
+	if u.Previous != nil {
+		previous = tools.SplitLines(u.Previous.Raw)
+	}
+
+	hunks := tools.Hunks(tools.Diff(previous, tools.SplitLines(u.Playlist.Raw)), diffContext)
+
+	output := new(bytes.Buffer)
+
+	if len(hunks) == 0 {
+		fmt.Fprint(output, "\r\nno changes since the last reload\r\n")
+		return output.String()
+	}
+
+	for _, h := range hunks {
+		fmt.Fprintf(output, "\r\n\033[38;5;44m%s\033[0m", h.Header())
+
+		for _, line := range h.Lines {
+			// Grey by default.
+			color := "\033[38;5;250m"
+
+			switch {
+			case line.Op != tools.DiffEqual && hls.IsPlaylistTag(line.Text):
+				color = "\033[38;5;226m"
+			case line.Op == tools.DiffInsert:
+				color = "\033[38;5;40m"
+			case line.Op == tools.DiffDelete:
+				color = "\033[38;5;160m"
+			}
+
+			fmt.Fprintf(output, "\r\n%s%s%s\033[0m", color, line.Op.Prefix(), line.Text)
+		}
+	}
+
+	fmt.Fprint(output, "\r\n")
+
+	return output.String()
+}
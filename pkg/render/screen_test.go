@@ -0,0 +1,70 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+	"github.com/moore0n/hlstail/pkg/tools"
+)
+
+// paneUpdates returns an update for each of names, a live playlist whose
+// last segment is the number given.
+func paneUpdates(last map[string]int, names ...string) []*hls.Update {
+	var updates []*hls.Update
+
+	for _, name := range names {
+		url := "http://example.com/" + name + ".m3u8"
+		raw := fmt.Sprintf("#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:%d\n", last[name]-1)
+
+		for sequence := last[name] - 1; sequence <= last[name]; sequence++ {
+			raw += fmt.Sprintf("#EXTINF:2.000,\n%s-%d.ts\n", name, sequence)
+		}
+
+		updates = append(updates, &hls.Update{
+			Time:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			URL:      url,
+			Playlist: hls.ParseMediaPlaylist(url, raw),
+		})
+	}
+
+	return updates
+}
+
+func TestPanesFrame(t *testing.T) {
+	updates := paneUpdates(map[string]int{"low": 10, "mid": 10, "high": 9}, "low", "mid", "high")
+
+	for _, width := range []int{0, 1, 5, 8, 20, 60, 200} {
+		t.Run(fmt.Sprint(width), func(t *testing.T) {
+			s := NewScreen(nil, 0, 0)
+			s.panes = updates
+
+			lines := strings.Split(strings.TrimSuffix(s.panesFrame(width, 10), "\r\n"), "\r\n")
+
+			// Everything but the actions fits the width.
+			for _, line := range lines[:len(lines)-1] {
+				if w := tools.Width(line); w > width {
+					t.Errorf("%q is %d columns", line, w)
+				}
+			}
+
+			// The header, titles, status and separator, the two sequences
+			// that fit, and the footer and actions each after a blank row.
+			if len(lines) != 10 {
+				t.Errorf("%d rows, want 10:\n%s", len(lines), strings.Join(lines, "\n"))
+			}
+		})
+	}
+
+	s := NewScreen(nil, 0, 0)
+	s.panes = updates
+	frame := tools.StripANSI(s.panesFrame(80, 24))
+
+	for _, want := range []string{"low-10.ts", "high-9.ts", "sequence 9, 1 behind"} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("no %q in the frame:\n%s", want, frame)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/moore0n/hlstail/pkg/hls"
+)
+
+// Text is an append only, line oriented renderer for output that is not a
+// terminal such as a pipe or a log file. Each line starts with the time of the
+// reload followed by the kind of event.
+type Text struct {
+	w      io.Writer
+	count  int
+	loaded map[string]bool
+}
+
+// NewText creates a Text renderer writing to w. The first reload prints the
+// last count segments, later reloads print every new segment.
+func NewText(w io.Writer, count int) *Text {
+	return &Text{
+		w:      w,
+		count:  count,
+		loaded: make(map[string]bool),
+	}
+}
+
+// Start does nothing, there is no screen to prepare.
+func (t *Text) Start() {}
+
+// Loading does nothing, only results are written.
+func (t *Text) Loading() {}
+
+// Variants writes the table of variants.
+func (t *Text) Variants(picker *Picker, selectedIndex int, marked []int) {
+	// Urls are written in full.
+	header, lines := picker.table(1 << 20)
+
+	fmt.Fprintln(t.w, header)
+
+	for _, line := range lines {
+		fmt.Fprintln(t.w, line)
+	}
+
+	for _, problem := range picker.Problems() {
+		fmt.Fprintf(t.w, "problem %s\n", problem)
+	}
+}
+
+// Update writes the tag changes and new segments of a reload.
+func (t *Text) Update(u *hls.Update) {
+	now := u.Time.UTC().Format(time.RFC3339)
+
+	if u.Err != nil {
+		fmt.Fprintf(t.w, "%s error %s\n", now, u.Err)
+		return
+	}
+
+	for _, tag := range u.Tags {
+		// The media sequence moves with every new segment.
+		if tag.Name == "EXTM3U" || tag.Name == "EXT-X-MEDIA-SEQUENCE" || tag.New == "" {
+			continue
+		}
+
+		fmt.Fprintf(t.w, "%s tag %s\n", now, tag.New)
+	}
+
+	added := u.Added
+
+	if !t.loaded[u.URL] && len(added) > t.count {
+		added = added[len(added)-t.count:]
+	}
+
+	for _, s := range added {
+		pdt := "-"
+
+		if !s.ProgramDateTime.IsZero() {
+			pdt = s.ProgramDateTime.UTC().Format(time.RFC3339Nano)
+		}
+
+		fmt.Fprintf(t.w, "%s segment %d %.3f %s %s\n", now, s.Sequence, s.Duration, pdt, s.URI)
+	}
+
+	t.loaded[u.URL] = true
+}
+
+// Panes writes the updates one after the other.
+func (t *Text) Panes(updates []*hls.Update) {
+	for _, u := range updates {
+		t.Update(u)
+	}
+}
+
+// Paused does nothing, a text renderer cannot be paused.
+func (t *Text) Paused() {}
+
+// Diff does nothing, a text renderer only shows what was added.
+func (t *Text) Diff(show bool) {}
+
+// Scroll does nothing, every segment is already written.
+func (t *Text) Scroll(pages int) {}
+
+// Search does nothing, every segment is already written.
+func (t *Text) Search(query string) {}
+
+// Prompt does nothing, there is no input to show.
+func (t *Text) Prompt(text string) {}
+
+// Notice writes the problem as a notice line, clearing it writes nothing.
+func (t *Text) Notice(text string) {
+	if text == "" {
+		return
+	}
+
+	fmt.Fprintf(t.w, "%s notice %s\n", time.Now().UTC().Format(time.RFC3339), text)
+}
+
+// Resize does nothing, lines are written once.
+func (t *Text) Resize() {}
+
+// End does nothing, there is no screen to restore.
+func (t *Text) End() {}
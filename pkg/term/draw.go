@@ -0,0 +1,48 @@
+package term
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Draw puts frame on the screen, a string of lines ending in \r\n. Only the
+// rows that differ from the last frame are written, in a single write, so
+// the screen does not flicker when it is redrawn. Lines past the bottom of
+// the screen are left out.
+func (s *Session) Draw(frame string) {
+	width, height := s.size()
+	lines := strings.Split(strings.TrimSuffix(frame, "\r\n"), "\r\n")
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	output := new(bytes.Buffer)
+
+	// Everything moves after a resize, start again with an empty screen.
+	if s.drawnWidth != width || s.drawnHeight != height || s.lines == nil {
+		s.lines = []string{}
+		fmt.Fprint(output, "\033[1;1H\033[2J")
+	}
+
+	s.drawnWidth, s.drawnHeight = width, height
+
+	for i, line := range lines {
+		if i < len(s.lines) && s.lines[i] == line {
+			continue
+		}
+
+		fmt.Fprintf(output, "\033[%d;1H\033[0m%s\033[0m\033[K", i+1, line)
+	}
+
+	if len(lines) < len(s.lines) {
+		fmt.Fprintf(output, "\033[%d;1H\033[0m\033[J", len(lines)+1)
+	}
+
+	s.lines = lines
+
+	if output.Len() > 0 {
+		fmt.Print(output.String())
+	}
+}
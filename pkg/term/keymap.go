@@ -0,0 +1,89 @@
+package term
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Keymap binds keys to named actions. A key may be bound to several actions
+// that are used on different screens.
+type Keymap struct {
+	bindings map[string][]string
+}
+
+// NewKeymap creates a Keymap from the keys bound to each action by default.
+func NewKeymap(defaults map[string][]string) *Keymap {
+	k := &Keymap{bindings: make(map[string][]string)}
+
+	for action, keys := range defaults {
+		k.bindings[action] = append([]string{}, keys...)
+	}
+
+	return k
+}
+
+// Load reads bindings from file. Each line is an action followed by the keys
+// to bind to it, which replace its default keys:
+//
+//	down = j down
+//	page-down = ctrl+f pgdn
+//
+// Blank lines and lines starting with # are ignored.
+func (k *Keymap) Load(file string) error {
+	f, err := os.Open(file)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	number := 0
+
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+
+		if len(parts) != 2 {
+			return fmt.Errorf("%s:%d: expected action = keys", file, number)
+		}
+
+		action := strings.TrimSpace(parts[0])
+
+		if _, ok := k.bindings[action]; !ok {
+			return fmt.Errorf("%s:%d: unknown action %q", file, number, action)
+		}
+
+		keys := strings.Fields(parts[1])
+
+		for _, key := range keys {
+			if !ValidKeyName(key) {
+				return fmt.Errorf("%s:%d: unknown key %q", file, number, key)
+			}
+		}
+
+		k.bindings[action] = keys
+	}
+
+	return scanner.Err()
+}
+
+// Is reports whether key is bound to action.
+func (k *Keymap) Is(key Key, action string) bool {
+	for _, name := range k.bindings[action] {
+		if name == key.Name {
+			return true
+		}
+	}
+
+	return false
+}
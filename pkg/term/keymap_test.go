@@ -0,0 +1,106 @@
+package term
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testBindings are the defaults of the keymaps in the tests.
+var testBindings = map[string][]string{
+	"quit": {"q", "ctrl+c"},
+	"down": {"down", "j"},
+}
+
+// writeKeymap writes text to a temporary keymap file, which the caller
+// removes.
+func writeKeymap(t *testing.T, text string) string {
+	t.Helper()
+
+	f, err := ioutil.TempFile("", "keymap")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	if _, err := f.WriteString(text); err != nil {
+		os.Remove(f.Name())
+		t.Fatal(err)
+	}
+
+	return f.Name()
+}
+
+func TestKeymapLoad(t *testing.T) {
+	k := NewKeymap(testBindings)
+	file := writeKeymap(t, "# vi keys\n\ndown = ctrl+n  pgdn\n")
+	defer os.Remove(file)
+
+	if err := k.Load(file); err != nil {
+		t.Fatalf("Load failed: %s", err)
+	}
+
+	tests := []struct {
+		key    string
+		action string
+		want   bool
+	}{
+		{"ctrl+n", "down", true},
+		{"pgdn", "down", true},
+		{"j", "down", false},
+		{"q", "quit", true},
+		{"ctrl+n", "quit", false},
+	}
+
+	for _, tt := range tests {
+		if got := k.Is(Key{Name: tt.key}, tt.action); got != tt.want {
+			t.Errorf("Is(%s, %s) = %t, want %t", tt.key, tt.action, got, tt.want)
+		}
+	}
+
+	// The defaults passed in are not changed by loading.
+	if testBindings["down"][1] != "j" {
+		t.Errorf("Load changed the defaults to %q", testBindings["down"])
+	}
+}
+
+func TestKeymapLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		err  string
+	}{
+		{"no equals", "down j\n", ":1: expected action = keys"},
+		{"unknown action", "# comment\njump = j\n", `:2: unknown action "jump"`},
+		{"unknown key", "down = j pagedown\n", `:1: unknown key "pagedown"`},
+		{"bad modifier", "quit = meta+q\n", `:1: unknown key "meta+q"`},
+		{"empty modifier", "quit = ctrl+\n", `:1: unknown key "ctrl+"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeKeymap(t, tt.text)
+			defer os.Remove(file)
+
+			k := NewKeymap(testBindings)
+			err := k.Load(file)
+
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Load = %v, want an error containing %q", err, tt.err)
+			}
+
+			// A bad file leaves the defaults alone.
+			if !k.Is(Key{Name: "j"}, "down") {
+				t.Errorf("j is no longer bound to down")
+			}
+		})
+	}
+
+	if err := NewKeymap(testBindings).Load(filepath.Join(os.TempDir(), "no-such-keymap")); err == nil {
+		t.Errorf("Load of a missing file succeeded")
+	}
+}
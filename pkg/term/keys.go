@@ -0,0 +1,384 @@
+package term
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// escapeTimeout is how long to wait for the rest of an escape sequence before
+// taking the escape as a key press of its own.
+const escapeTimeout = 50 * time.Millisecond
+
+// KeyResize is the name of the Key sent when the terminal is resized, the
+// screen should be drawn again at the new size.
+const KeyResize = "resize"
+
+// Key is a key press, or mouse event, decoded from terminal input. Name is
+// how the key is written in a keymap: a printable character is its own name
+// and other keys have names such as up, pgdn, f5, enter, space or ctrl+c.
+type Key struct {
+	Name string
+
+	// Rune is the character typed, 0 for keys that do not type one.
+	Rune rune
+
+	// X and Y are the cell of a mouse event, counted from 1.
+	X int
+	Y int
+}
+
+// keyNames are the names of the keys that are not printable characters.
+var keyNames = []string{
+	"up", "down", "left", "right", "home", "end", "pgup", "pgdn", "insert", "delete",
+	"enter", "tab", "backspace", "esc", "space",
+	"f1", "f2", "f3", "f4", "f5", "f6", "f7", "f8", "f9", "f10", "f11", "f12",
+	"wheelup", "wheeldown", "click",
+}
+
+// ValidKeyName reports whether name is a key that can be decoded, optionally
+// with ctrl+, alt+ or shift+ in front.
+func ValidKeyName(name string) bool {
+	for _, modifier := range []string{"ctrl+", "alt+", "shift+"} {
+		if strings.HasPrefix(name, modifier) && len(name) > len(modifier) {
+			return ValidKeyName(name[len(modifier):])
+		}
+	}
+
+	if utf8.RuneCountInString(name) == 1 {
+		return true
+	}
+
+	for _, n := range keyNames {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadKeys reads stdin on a single goroutine for the lifetime of the session
+// and delivers each key on the returned channel, along with a KeyResize when
+// the terminal is resized. The channel is closed when stdin returns an error
+// or ctx is cancelled.
+func (s *Session) ReadKeys(ctx context.Context) <-chan Key {
+	chunks := make(chan []byte)
+	keys := make(chan Key)
+
+	go func() {
+		defer close(chunks)
+
+		buf := make([]byte, 256)
+
+		for {
+			n, err := os.Stdin.Read(buf)
+
+			if n > 0 {
+				select {
+				case chunks <- append([]byte{}, buf[:n]...):
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer close(keys)
+
+		// A single buffer keeps input from being lost between screens.
+		var pending []byte
+
+		send := func(k Key) bool {
+			if k.Name == "" {
+				return true
+			}
+
+			select {
+			case keys <- k:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			for len(pending) > 0 {
+				k, n := ParseKey(pending, false)
+
+				if n == 0 {
+					break
+				}
+
+				pending = pending[n:]
+
+				if !send(k) {
+					return
+				}
+			}
+
+			var timeout <-chan time.Time
+
+			// The rest of an escape sequence usually arrives with it, if it
+			// does not the escape key was pressed on its own.
+			if len(pending) > 0 {
+				timeout = time.After(escapeTimeout)
+			}
+
+			select {
+			case chunk, ok := <-chunks:
+				if !ok {
+					return
+				}
+
+				pending = append(pending, chunk...)
+			case <-timeout:
+				k, n := ParseKey(pending, true)
+				pending = pending[n:]
+
+				if !send(k) {
+					return
+				}
+			case <-s.resized:
+				if !send(Key{Name: KeyResize}) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return keys
+}
+
+// ParseKey decodes the key at the start of input and returns it with the
+// number of bytes it used. When input holds the start of an escape sequence
+// that has not been read in full 0 is returned, unless final is set in which
+// case whatever is there is decoded. Sequences that are not understood are
+// skipped and return a Key with no name.
+func ParseKey(input []byte, final bool) (Key, int) {
+	if len(input) == 0 {
+		return Key{}, 0
+	}
+
+	b := input[0]
+
+	switch {
+	case b == 0x1b:
+		return parseEscape(input, final)
+	case b == '\r' || b == '\n':
+		return Key{Name: "enter"}, 1
+	case b == '\t':
+		return Key{Name: "tab"}, 1
+	case b == 0x7f || b == 0x08:
+		return Key{Name: "backspace"}, 1
+	case b == 0:
+		return Key{Name: "ctrl+space"}, 1
+	case b < 0x1b:
+		return Key{Name: "ctrl+" + string(rune('a'+b-1))}, 1
+	case b < ' ':
+		return Key{Name: "ctrl+" + string(rune('\\'+b-0x1c))}, 1
+	case b == ' ':
+		return Key{Name: "space", Rune: ' '}, 1
+	}
+
+	if !utf8.FullRune(input) && !final {
+		return Key{}, 0
+	}
+
+	r, n := utf8.DecodeRune(input)
+
+	if r == utf8.RuneError {
+		return Key{}, n
+	}
+
+	return Key{Name: string(r), Rune: r}, n
+}
+
+// parseEscape decodes input starting with an escape.
+func parseEscape(input []byte, final bool) (Key, int) {
+	if len(input) == 1 {
+		if final {
+			return Key{Name: "esc"}, 1
+		}
+
+		return Key{}, 0
+	}
+
+	switch input[1] {
+	case '[':
+		return parseCSI(input, final)
+	case 'O':
+		// SS3, sent for the arrows and F1 to F4 in application mode.
+		if len(input) < 3 {
+			if final {
+				return Key{Name: "alt+O", Rune: 'O'}, 2
+			}
+
+			return Key{}, 0
+		}
+
+		return Key{Name: finalKeys[input[2]]}, 3
+	case 0x1b:
+		return Key{Name: "esc"}, 1
+	}
+
+	// Escape in front of a key is how terminals send alt.
+	k, n := ParseKey(input[1:], final)
+
+	if n == 0 {
+		return Key{}, 0
+	}
+
+	if k.Name != "" {
+		k.Name = "alt+" + k.Name
+	}
+
+	return k, n + 1
+}
+
+// finalKeys are the keys named by the last byte of a CSI or SS3 sequence.
+var finalKeys = map[byte]string{
+	'A': "up",
+	'B': "down",
+	'C': "right",
+	'D': "left",
+	'H': "home",
+	'F': "end",
+	'P': "f1",
+	'Q': "f2",
+	'R': "f3",
+	'S': "f4",
+	'Z': "shift+tab",
+}
+
+// tildeKeys are the keys of the CSI sequences ending in ~ by their first
+// parameter.
+var tildeKeys = map[int]string{
+	1: "home", 2: "insert", 3: "delete", 4: "end", 5: "pgup", 6: "pgdn", 7: "home", 8: "end",
+	11: "f1", 12: "f2", 13: "f3", 14: "f4", 15: "f5", 17: "f6", 18: "f7", 19: "f8", 20: "f9", 21: "f10", 23: "f11", 24: "f12",
+}
+
+// modifierNames are the prefixes of the xterm modifier parameter, 1 plus the
+// sum of shift 1, alt 2 and ctrl 4.
+var modifierNames = map[int]string{
+	2: "shift+", 3: "alt+", 4: "alt+shift+", 5: "ctrl+", 6: "ctrl+shift+", 7: "ctrl+alt+", 8: "ctrl+alt+shift+",
+}
+
+// parseCSI decodes an escape sequence starting with ESC [.
+func parseCSI(input []byte, final bool) (Key, int) {
+	// X10 mouse reports are ESC [ M followed by three bytes.
+	if len(input) >= 3 && input[2] == 'M' {
+		if len(input) < 6 {
+			if final {
+				return Key{}, len(input)
+			}
+
+			return Key{}, 0
+		}
+
+		return mouseKey(int(input[3])-32, int(input[4])-32, int(input[5])-32, true), 6
+	}
+
+	end := -1
+
+	for i := 2; i < len(input); i++ {
+		if input[i] >= 0x40 && input[i] <= 0x7e {
+			end = i
+			break
+		}
+	}
+
+	if end < 0 {
+		if final {
+			return Key{}, len(input)
+		}
+
+		return Key{}, 0
+	}
+
+	params := string(input[2:end])
+	last := input[end]
+
+	// SGR mouse reports are ESC [ < button ; x ; y followed by M for a press
+	// or m for a release.
+	if strings.HasPrefix(params, "<") && (last == 'M' || last == 'm') {
+		fields := numbers(params[1:])
+
+		if len(fields) != 3 {
+			return Key{}, end + 1
+		}
+
+		return mouseKey(fields[0], fields[1], fields[2], last == 'M'), end + 1
+	}
+
+	fields := numbers(params)
+
+	var name string
+
+	if last == '~' {
+		if len(fields) > 0 {
+			name = tildeKeys[fields[0]]
+		}
+	} else {
+		name = finalKeys[last]
+	}
+
+	if name == "" {
+		return Key{}, end + 1
+	}
+
+	if len(fields) > 1 {
+		name = modifierNames[fields[1]] + name
+	}
+
+	return Key{Name: name}, end + 1
+}
+
+// mouseKey names a mouse report, only presses of the left button and the
+// wheel are reported.
+func mouseKey(button int, x int, y int, press bool) Key {
+	k := Key{X: x, Y: y}
+
+	switch {
+	case button&64 != 0 && button&1 == 0:
+		k.Name = "wheelup"
+	case button&64 != 0:
+		k.Name = "wheeldown"
+	case press && button&3 == 0 && button&32 == 0:
+		k.Name = "click"
+	}
+
+	return k
+}
+
+// numbers splits the ; separated parameters of an escape sequence, a missing
+// parameter is 1.
+func numbers(params string) []int {
+	if params == "" {
+		return nil
+	}
+
+	var fields []int
+
+	for _, p := range strings.Split(params, ";") {
+		n, err := strconv.Atoi(p)
+
+		if err != nil {
+			n = 1
+		}
+
+		fields = append(fields, n)
+	}
+
+	return fields
+}
@@ -0,0 +1,93 @@
+package term
+
+import "testing"
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		final bool
+		want  Key
+		n     int
+	}{
+		{"character", "a", false, Key{Name: "a", Rune: 'a'}, 1},
+		{"wide character", "日x", false, Key{Name: "日", Rune: '日'}, 3},
+		{"enter", "\r", false, Key{Name: "enter"}, 1},
+		{"space", " ", false, Key{Name: "space", Rune: ' '}, 1},
+		{"ctrl", "\x06", false, Key{Name: "ctrl+f"}, 1},
+		{"backspace", "\x7f", false, Key{Name: "backspace"}, 1},
+
+		{"up", "\x1b[A", false, Key{Name: "up"}, 3},
+		{"ctrl up", "\x1b[1;5A", false, Key{Name: "ctrl+up"}, 6},
+		{"shift down", "\x1b[1;2B", false, Key{Name: "shift+down"}, 6},
+		{"alt right", "\x1b[1;3C", false, Key{Name: "alt+right"}, 6},
+		{"ctrl alt shift left", "\x1b[1;8D", false, Key{Name: "ctrl+alt+shift+left"}, 6},
+		{"application up", "\x1bOA", false, Key{Name: "up"}, 3},
+
+		{"csi home", "\x1b[H", false, Key{Name: "home"}, 3},
+		{"csi end", "\x1b[F", false, Key{Name: "end"}, 3},
+		{"ss3 home", "\x1bOH", false, Key{Name: "home"}, 3},
+		{"ss3 end", "\x1bOF", false, Key{Name: "end"}, 3},
+		{"tilde home", "\x1b[1~", false, Key{Name: "home"}, 4},
+		{"tilde end", "\x1b[4~", false, Key{Name: "end"}, 4},
+		{"rxvt home", "\x1b[7~", false, Key{Name: "home"}, 4},
+		{"rxvt end", "\x1b[8~", false, Key{Name: "end"}, 4},
+		{"pgup", "\x1b[5~", false, Key{Name: "pgup"}, 4},
+		{"pgdn", "\x1b[6~", false, Key{Name: "pgdn"}, 4},
+		{"ctrl pgdn", "\x1b[6;5~", false, Key{Name: "ctrl+pgdn"}, 6},
+		{"f5", "\x1b[15~", false, Key{Name: "f5"}, 5},
+		{"unknown sequence", "\x1b[99~x", false, Key{}, 5},
+
+		{"bare esc waiting", "\x1b", false, Key{}, 0},
+		{"bare esc", "\x1b", true, Key{Name: "esc"}, 1},
+		{"esc esc", "\x1b\x1b[A", false, Key{Name: "esc"}, 1},
+		{"alt key", "\x1bx", false, Key{Name: "alt+x", Rune: 'x'}, 2},
+		{"alt ctrl key", "\x1b\x06", false, Key{Name: "alt+ctrl+f"}, 2},
+		{"alt O waiting", "\x1bO", false, Key{}, 0},
+		{"alt O", "\x1bO", true, Key{Name: "alt+O", Rune: 'O'}, 2},
+		{"alt of a split rune", "\x1b\xe6\x97", false, Key{}, 0},
+
+		{"truncated csi", "\x1b[", false, Key{}, 0},
+		{"truncated csi final", "\x1b[", true, Key{}, 2},
+		{"truncated modifier", "\x1b[1;5", false, Key{}, 0},
+		{"truncated modifier final", "\x1b[1;5", true, Key{}, 5},
+		{"truncated rune", "\xe6\x97", false, Key{}, 0},
+		{"invalid rune", "\xff", false, Key{}, 1},
+
+		{"sgr wheel up", "\x1b[<64;10;5M", false, Key{Name: "wheelup", X: 10, Y: 5}, 11},
+		{"sgr wheel down", "\x1b[<65;1;2M", false, Key{Name: "wheeldown", X: 1, Y: 2}, 10},
+		{"sgr click", "\x1b[<0;3;4M", false, Key{Name: "click", X: 3, Y: 4}, 9},
+		{"sgr release", "\x1b[<0;3;4m", false, Key{X: 3, Y: 4}, 9},
+		{"sgr right click", "\x1b[<2;3;4M", false, Key{X: 3, Y: 4}, 9},
+		{"sgr truncated", "\x1b[<64;10", false, Key{}, 0},
+		{"x10 wheel up", "\x1b[M" + string([]byte{32 + 64, 32 + 10, 32 + 5}), false, Key{Name: "wheelup", X: 10, Y: 5}, 6},
+		{"x10 wheel down", "\x1b[M" + string([]byte{32 + 65, 33, 33}), false, Key{Name: "wheeldown", X: 1, Y: 1}, 6},
+		{"x10 click", "\x1b[M" + string([]byte{32, 40, 50}), false, Key{Name: "click", X: 8, Y: 18}, 6},
+		{"x10 truncated", "\x1b[M ", false, Key{}, 0},
+		{"x10 truncated final", "\x1b[M ", true, Key{}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n := ParseKey([]byte(tt.input), tt.final)
+
+			if got != tt.want || n != tt.n {
+				t.Errorf("ParseKey(%q, %t) = %+v, %d, want %+v, %d", tt.input, tt.final, got, n, tt.want, tt.n)
+			}
+		})
+	}
+}
+
+func TestValidKeyName(t *testing.T) {
+	for _, name := range []string{"a", "G", "/", "日", "pgdn", "f12", "ctrl+f", "alt+shift+up", "wheelup", "space"} {
+		if !ValidKeyName(name) {
+			t.Errorf("ValidKeyName(%q) = false, want true", name)
+		}
+	}
+
+	for _, name := range []string{"", "ctrl+", "pagedown", "f13", "ctrl+xy", "meta+a"} {
+		if ValidKeyName(name) {
+			t.Errorf("ValidKeyName(%q) = true, want false", name)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package term
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchResize updates the size of the terminal whenever it sends SIGWINCH.
+func (s *Session) watchResize() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+
+	go func() {
+		for range signals {
+			s.updateSize()
+		}
+	}()
+}
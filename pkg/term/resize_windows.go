@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package term
+
+import "time"
+
+// resizePoll is how often the size of the console is checked, windows has no
+// signal for a resize.
+const resizePoll = 500 * time.Millisecond
+
+// watchResize updates the size of the console while the program runs.
+func (s *Session) watchResize() {
+	go func() {
+		for range time.Tick(resizePoll) {
+			s.updateSize()
+		}
+	}()
+}
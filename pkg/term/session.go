@@ -3,21 +3,47 @@ package term
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"golang.org/x/crypto/ssh/terminal"
 )
 
+// defaultWidth and defaultHeight are used when the size of the terminal
+// can't be determined.
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// IsTerminal checks if the file is attached to a terminal.
+func IsTerminal(f *os.File) bool {
+	return terminal.IsTerminal(int(f.Fd()))
+}
+
 // Session tracks the current terminal session.
 type Session struct {
 	PreviousState *terminal.State
 	StdinFd       int
-	Paused        bool
-	Reset         bool
+	mouse         bool
+
+	// mu guards the size, which is updated when the terminal is resized.
+	mu     sync.Mutex
+	width  int
+	height int
+
+	// resized is signalled after the size changes.
+	resized chan struct{}
+
+	// lines is what Draw last put on the screen, one entry per row, at the
+	// size it was drawn for.
+	lines       []string
+	drawnWidth  int
+	drawnHeight int
 }
 
 // NewSession creates a new session
 func NewSession() *Session {
-	return &Session{}
+	return &Session{resized: make(chan struct{}, 1)}
 }
 
 // MakeRaw sets the terminal in raw mode.
@@ -35,28 +61,87 @@ func (s *Session) MakeRaw() error {
 	s.PreviousState = previousState
 	s.StdinFd = fd
 
+	s.updateSize()
+	s.watchResize()
+
 	return nil
 }
 
-// GetCliWidth returns the available screen space
+// GetCliWidth returns the available screen space, falling back to a default
+// width when stdout is not a terminal.
 func (s *Session) GetCliWidth() int {
-	width, _, err := terminal.GetSize(s.StdinFd)
+	width, _ := s.size()
 
-	if err != nil {
-		s.End()
-		os.Exit(1)
+	return width
+}
+
+// GetCliHeight returns the number of rows on the screen, falling back to a
+// default height when stdout is not a terminal.
+func (s *Session) GetCliHeight() int {
+	_, height := s.size()
+
+	return height
+}
+
+// size returns the size of the terminal, which is only queried again when it
+// is resized.
+func (s *Session) size() (int, int) {
+	s.mu.Lock()
+	width, height := s.width, s.height
+	s.mu.Unlock()
+
+	if width == 0 {
+		return s.updateSize()
 	}
 
-	return width
+	return width, height
+}
+
+// updateSize queries the size of the terminal and reports whether it changed.
+func (s *Session) updateSize() (int, int) {
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = defaultWidth, defaultHeight
+	}
+
+	s.mu.Lock()
+	changed := s.width != 0 && (s.width != width || s.height != height)
+	s.width, s.height = width, height
+	s.mu.Unlock()
+
+	if changed {
+		// A resize already waiting to be handled covers this one.
+		select {
+		case s.resized <- struct{}{}:
+		default:
+		}
+	}
+
+	return width, height
 }
 
 // End returns the terminal to its state from before hlstail started.
 func (s *Session) End() {
-	fmt.Print("\033[1;1H\033[0J\033[?25h")
+	if s.mouse {
+		fmt.Print("\033[?1006l\033[?1000l")
+	}
+
+	// Leaving the alternate screen puts back what was there before.
+	fmt.Print("\033[?7h\033[?25h\033[?1049l")
 	terminal.Restore(s.StdinFd, s.PreviousState)
 }
 
-// Start will setup the stdout for printing.
+// EnableMouse asks the terminal to report mouse clicks and the wheel as
+// keys, until End.
+func (s *Session) EnableMouse() {
+	s.mouse = true
+	fmt.Print("\033[?1000h\033[?1006h")
+}
+
+// Start will setup the stdout for printing on the alternate screen, with
+// line wrapping turned off so every line of a frame takes a single row.
 func (s *Session) Start() {
-	fmt.Print("\033[1;1H\033[0J\033[?25l")
+	s.lines = nil
+	fmt.Print("\033[?1049h\033[?7l\033[1;1H\033[2J\033[?25l")
 }
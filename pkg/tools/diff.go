@@ -0,0 +1,308 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DiffOp is what happened to a line between two texts.
+type DiffOp int
+
+// Diff operations.
+const (
+	DiffEqual DiffOp = iota
+	DiffDelete
+	DiffInsert
+)
+
+// DiffLine is a line of a diff.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// Hunk is a group of changes with the lines around them. The starts are zero
+// based line numbers in a and b.
+type Hunk struct {
+	StartA int
+	LenA   int
+	StartB int
+	LenB   int
+	Lines  []DiffLine
+}
+
+// Header returns the @@ line of the hunk in unified diff format.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.StartA, h.LenA), hunkRange(h.StartB, h.LenB))
+}
+
+func hunkRange(start int, length int) string {
+	// Empty ranges point at the line before them.
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// maxDiffEdits is the most edits Diff looks for, past it the texts are too
+// different for a shortest edit to be worth the time and memory.
+const maxDiffEdits = 1000
+
+// Diff returns the shortest edit from a to b using the Myers algorithm. When
+// more than maxDiffEdits lines differ it returns the lines between the first
+// and last change of a deleted and those of b inserted instead.
+func Diff(a []string, b []string) []DiffLine {
+	// The lines both texts start and end with are equal whatever comes
+	// between them, and usually most of a playlist.
+	prefix := 0
+
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	lines = appendLines(lines, DiffEqual, a[:prefix])
+
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if middle, ok := myers(middleA, middleB); ok {
+		lines = append(lines, middle...)
+	} else {
+		lines = appendLines(lines, DiffDelete, middleA)
+		lines = appendLines(lines, DiffInsert, middleB)
+	}
+
+	return appendLines(lines, DiffEqual, a[len(a)-suffix:])
+}
+
+// appendLines appends each of texts to lines with op.
+func appendLines(lines []DiffLine, op DiffOp, texts []string) []DiffLine {
+	for _, text := range texts {
+		lines = append(lines, DiffLine{Op: op, Text: text})
+	}
+
+	return lines
+}
+
+// myers returns the shortest edit from a to b, or false if it takes more
+// than maxDiffEdits.
+func myers(a []string, b []string) ([]DiffLine, bool) {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+
+	// The first step reads one past the diagonals, there is room for it even
+	// when both texts are empty.
+	v := make([]int, 2*max+3)
+	trace := make([][]int, 0)
+
+	// Walk the edit graph until we reach the end of both texts, saving the
+	// diagonals each step can reach so the path can be followed back.
+	found := false
+
+	for d := 0; d <= max && !found; d++ {
+		if d > maxDiffEdits {
+			return nil, false
+		}
+
+		trace = append(trace, append([]int{}, v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// Follow the path back from the end.
+	lines := make([]DiffLine, 0, max)
+	x, y := n, m
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		// Step d saved the diagonals -d-1 to d+1.
+		v := trace[d]
+		offset := d + 1
+		k := x - y
+
+		var prevK int
+
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: a[x]})
+		}
+
+		if d == 0 {
+			break
+		}
+
+		if x == prevX {
+			y--
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: b[y]})
+		} else {
+			x--
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: a[x]})
+		}
+	}
+
+	// The lines were collected backwards.
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	return lines, true
+}
+
+// Hunks groups the changes of a diff with up to context unchanged lines
+// around them. Changes closer than twice the context share a hunk.
+func Hunks(lines []DiffLine, context int) []Hunk {
+	hunks := make([]Hunk, 0)
+
+	// Find the indexes of every change.
+	changes := make([]int, 0)
+
+	for i, line := range lines {
+		if line.Op != DiffEqual {
+			changes = append(changes, i)
+		}
+	}
+
+	for c := 0; c < len(changes); {
+		start := changes[c] - context
+
+		if start < 0 {
+			start = 0
+		}
+
+		end := changes[c]
+
+		// Extend the hunk while the next change is close enough.
+		for c < len(changes) && changes[c]-end <= 2*context+1 {
+			end = changes[c]
+			c++
+		}
+
+		end += context + 1
+
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		h := Hunk{Lines: lines[start:end]}
+
+		// Count the lines of each text before and inside the hunk.
+		for _, line := range lines[:start] {
+			if line.Op != DiffInsert {
+				h.StartA++
+			}
+
+			if line.Op != DiffDelete {
+				h.StartB++
+			}
+		}
+
+		for _, line := range h.Lines {
+			if line.Op != DiffInsert {
+				h.LenA++
+			}
+
+			if line.Op != DiffDelete {
+				h.LenB++
+			}
+		}
+
+		hunks = append(hunks, h)
+	}
+
+	return hunks
+}
+
+// UnifiedDiff returns the diff of a and b in unified diff format, or an empty
+// string when they are the same.
+func UnifiedDiff(nameA string, nameB string, a []string, b []string, context int) string {
+	hunks := Hunks(Diff(a, b), context)
+
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	output := new(bytes.Buffer)
+
+	fmt.Fprintf(output, "--- %s\n+++ %s\n", nameA, nameB)
+
+	for _, h := range hunks {
+		fmt.Fprintf(output, "%s\n", h.Header())
+
+		for _, line := range h.Lines {
+			fmt.Fprintf(output, "%s%s\n", line.Op.Prefix(), line.Text)
+		}
+	}
+
+	return output.String()
+}
+
+// Prefix returns the character that marks the operation in a unified diff.
+func (op DiffOp) Prefix() string {
+	switch op {
+	case DiffDelete:
+		return "-"
+	case DiffInsert:
+		return "+"
+	}
+
+	return " "
+}
+
+// SplitLines splits text such as a playlist into lines, ignoring blank ones.
+func SplitLines(raw string) []string {
+	lines := make([]string, 0)
+
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		edits int
+	}{
+		{"equal", "a b c", "a b c", 0},
+		{"both empty", "", "", 0},
+		{"all inserted", "", "a b", 2},
+		{"all deleted", "a b", "", 2},
+		{"changed line", "a b c", "a x c", 2},
+		{"appended", "a b c", "a b c d e", 2},
+		{"slid window", "1 2 3 4", "3 4 5 6", 4},
+		{"moved line", "a b c d", "b c d a", 2},
+		{"changes between equal ends", "a b c d e", "a x c y e", 4},
+		{"repeated lines", "a a b a a", "a a a a", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if edits := diffEdits(t, strings.Fields(tt.a), strings.Fields(tt.b)); edits != tt.edits {
+				t.Errorf("Diff(%q, %q) has %d edits, want %d", tt.a, tt.b, edits, tt.edits)
+			}
+		})
+	}
+}
+
+func TestDiffLarge(t *testing.T) {
+	numbered := func(n int, changed func(i int) bool) []string {
+		lines := make([]string, n)
+
+		for i := range lines {
+			lines[i] = fmt.Sprintf("%d.ts", i)
+
+			if changed(i) {
+				lines[i] = "changed-" + lines[i]
+			}
+		}
+
+		return lines
+	}
+
+	none := func(i int) bool { return false }
+
+	tests := []struct {
+		name  string
+		a, b  []string
+		edits int
+	}{
+		{"one change", numbered(100000, none), numbered(100000, func(i int) bool { return i == 50000 }), 2},
+		{"slid window", numbered(3000, none)[:2990], numbered(3000, none)[10:], 20},
+		{"changes at both ends", numbered(3000, none), numbered(3000, func(i int) bool { return i < 200 || i >= 2800 }), 800},
+
+		// Too many changes for a shortest edit, everything from the first
+		// change to the last is replaced.
+		{"every other line", numbered(3000, none), numbered(3000, func(i int) bool { return i%2 == 1 }), 2 * 2999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if edits := diffEdits(t, tt.a, tt.b); edits != tt.edits {
+				t.Errorf("Diff has %d edits, want %d", edits, tt.edits)
+			}
+		})
+	}
+}
+
+// diffEdits checks that the diff of a and b turns a into b and returns the
+// number of lines deleted or inserted.
+func diffEdits(t *testing.T, a []string, b []string) int {
+	t.Helper()
+
+	// Equal and deleted lines rebuild a, equal and inserted ones b.
+	var gotA, gotB []string
+	edits := 0
+
+	for _, line := range Diff(a, b) {
+		if line.Op != DiffInsert {
+			gotA = append(gotA, line.Text)
+		}
+
+		if line.Op != DiffDelete {
+			gotB = append(gotB, line.Text)
+		}
+
+		if line.Op != DiffEqual {
+			edits++
+		}
+	}
+
+	if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+		t.Errorf("Diff of %d and %d lines rebuilds %d and %d that differ", len(a), len(b), len(gotA), len(gotB))
+	}
+
+	return edits
+}
+
+func TestHunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		headers []string
+	}{
+		{"no changes", "a b c", "a b c", 2, nil},
+		{"one change", "a b c d e f g", "a b c x e f g", 1, []string{"@@ -3,3 +3,3 @@"}},
+		{"context at the start", "a b c", "x b c", 2, []string{"@@ -1,3 +1,3 @@"}},
+		{"changes far apart", "a b c d e f g h", "x b c d e f g y", 1, []string{"@@ -1,2 +1,2 @@", "@@ -7,2 +7,2 @@"}},
+		{"changes close together", "a b c d e", "x b c d y", 2, []string{"@@ -1,5 +1,5 @@"}},
+		{"insert into empty", "", "a", 2, []string{"@@ -0,0 +1 @@"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+
+			for _, h := range Hunks(Diff(strings.Fields(tt.a), strings.Fields(tt.b)), tt.context) {
+				headers = append(headers, h.Header())
+			}
+
+			if !reflect.DeepEqual(headers, tt.headers) {
+				t.Errorf("hunks %q, want %q", headers, tt.headers)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := []string{"#EXTM3U", "#EXT-X-MEDIA-SEQUENCE:1", "1.ts", "2.ts"}
+	b := []string{"#EXTM3U", "#EXT-X-MEDIA-SEQUENCE:2", "2.ts", "3.ts"}
+
+	want := "--- a\n+++ b\n@@ -1,4 +1,4 @@\n #EXTM3U\n-#EXT-X-MEDIA-SEQUENCE:1\n-1.ts\n+#EXT-X-MEDIA-SEQUENCE:2\n 2.ts\n+3.ts\n"
+
+	if got := UnifiedDiff("a", "b", a, b, 1); got != want {
+		t.Errorf("UnifiedDiff =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := UnifiedDiff("a", "b", a, a, 1); got != "" {
+		t.Errorf("UnifiedDiff of equal texts = %q, want nothing", got)
+	}
+}
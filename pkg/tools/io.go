@@ -7,19 +7,15 @@ import (
 	"strings"
 )
 
-// PrintBuffer prints the value of val to stdout
-func PrintBuffer(val interface{}) {
-	fmt.Printf("\033[1;1H\033[0J%v", val)
-}
-
-// PadString returns a new padded string
+// PadString returns content centered in width columns, filled either side with
+// char. Content that doesn't fit is cut short.
 func PadString(content string, width int, char string) string {
 	paddingRoom := width / 2
-	strLength := len(content)
 
-	if strLength != 0 {
+	if content != "" {
 		// -2 is a space on either side.
-		paddingRoom = (width - strLength - 2) / 2
+		content = Truncate(content, width-2)
+		paddingRoom = (width - Width(content) - 2) / 2
 	}
 
 	rawPadding := []string{}
@@ -38,7 +34,7 @@ func PadString(content string, width int, char string) string {
 
 	// In the case that we have an odd width or odd content we want to make sure
 	// it truly stretches the width. It should only ever be off by 1.
-	if len(result) != width {
+	if Width(result) < width {
 		result = fmt.Sprintf("%s%s", result, char)
 	}
 
@@ -89,13 +85,13 @@ func LogToFile(val interface{}) {
 	f.WriteString(s)
 }
 
-// PrintLoading takes over the screen with loading feedback
-func PrintLoading(width int) {
+// GetLoading returns a screen of loading feedback.
+func GetLoading(width int) string {
 	output := new(bytes.Buffer)
 
 	fmt.Fprint(output, GetHeader(width, ""))
 	fmt.Fprint(output, "\r\nLoading...\r\n\r\n")
 	fmt.Fprint(output, GetFooter(width, ""))
 
-	PrintBuffer(output.String())
+	return output.String()
 }
@@ -0,0 +1,346 @@
+package tools
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ellipsis marks where text was cut to fit.
+const Ellipsis = "…"
+
+// wideRanges are the East Asian wide and fullwidth characters, and emoji,
+// which take two columns of the terminal.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f},
+	{0x231a, 0x231b},
+	{0x2329, 0x232a},
+	{0x23e9, 0x23ec},
+	{0x23f0, 0x23f0},
+	{0x23f3, 0x23f3},
+	{0x25fd, 0x25fe},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x26aa, 0x26ab},
+	{0x26bd, 0x26be},
+	{0x26c4, 0x26c5},
+	{0x26f5, 0x26f5},
+	{0x26fa, 0x26fa},
+	{0x26fd, 0x26fd},
+	{0x2705, 0x2705},
+	{0x270a, 0x270b},
+	{0x2728, 0x2728},
+	{0x274c, 0x274c},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x2b1b, 0x2b1c},
+	{0x2b50, 0x2b50},
+	{0x2b55, 0x2b55},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xa960, 0xa97f},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe10, 0xfe19},
+	{0xfe30, 0xfe6f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x16fe0, 0x16fe4},
+	{0x17000, 0x18cff},
+	{0x1b000, 0x1b16f},
+	{0x1f004, 0x1f004},
+	{0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e},
+	{0x1f191, 0x1f19a},
+	{0x1f200, 0x1f251},
+	{0x1f300, 0x1f64f},
+	{0x1f680, 0x1f6ff},
+	{0x1f7e0, 0x1f7eb},
+	{0x1f90c, 0x1f9ff},
+	{0x1fa70, 0x1faff},
+	{0x20000, 0x2fffd},
+	{0x30000, 0x3fffd},
+}
+
+// RuneWidth returns the number of columns r takes on the terminal: 0 for
+// control characters, combining marks and other characters that are not
+// drawn on their own, 2 for wide characters and 1 for the rest.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case r < 0x300:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || r == 0x200b:
+		return 0
+	}
+
+	for _, wide := range wideRanges {
+		if r < wide[0] {
+			break
+		}
+
+		if r <= wide[1] {
+			return 2
+		}
+	}
+
+	return 1
+}
+
+// escapeLength returns the length of the ANSI escape sequence at the start of
+// s, or 0 if s does not start with one.
+func escapeLength(s string) int {
+	if len(s) < 2 || s[0] != 0x1b {
+		return 0
+	}
+
+	if s[1] != '[' {
+		return 2
+	}
+
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+
+	return len(s)
+}
+
+// StripANSI removes the ANSI escape sequences, such as colors, from s.
+func StripANSI(s string) string {
+	if !strings.Contains(s, "\033") {
+		return s
+	}
+
+	output := new(strings.Builder)
+
+	for i := 0; i < len(s); {
+		if n := escapeLength(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+
+		output.WriteByte(s[i])
+		i++
+	}
+
+	return output.String()
+}
+
+// Width returns the number of columns s takes on the terminal, ANSI escape
+// sequences take none.
+func Width(s string) int {
+	width := 0
+
+	for i := 0; i < len(s); {
+		if n := escapeLength(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		width += RuneWidth(r)
+		i += size
+	}
+
+	return width
+}
+
+// Pad fills s with spaces on the right to width columns. Text that is already
+// wider is left as it is.
+func Pad(s string, width int) string {
+	if w := Width(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+
+	return s
+}
+
+// PadLeft fills s with spaces on the left to width columns.
+func PadLeft(s string, width int) string {
+	if w := Width(s); w < width {
+		return strings.Repeat(" ", width-w) + s
+	}
+
+	return s
+}
+
+// Truncate cuts s to at most width columns, ending it with an ellipsis when
+// anything was cut. ANSI escape sequences are kept, and the colors reset
+// after the cut if there were any.
+func Truncate(s string, width int) string {
+	if Width(s) <= width {
+		return s
+	}
+
+	if width <= 0 {
+		return ""
+	}
+
+	output := new(strings.Builder)
+	escaped := false
+	used := 0
+
+	for i := 0; i < len(s); {
+		if n := escapeLength(s[i:]); n > 0 {
+			output.WriteString(s[i : i+n])
+			escaped = true
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := RuneWidth(r)
+
+		// Leave a column for the ellipsis.
+		if used+w > width-1 {
+			break
+		}
+
+		output.WriteString(s[i : i+size])
+		used += w
+		i += size
+	}
+
+	output.WriteString(Ellipsis)
+
+	if escaped {
+		output.WriteString("\033[0m")
+	}
+
+	return output.String()
+}
+
+// TruncateMiddle cuts s to at most width columns by replacing its middle with
+// an ellipsis, keeping both the start and the end, as a url is told apart by
+// its host and its file name. ANSI escape sequences are removed.
+func TruncateMiddle(s string, width int) string {
+	s = StripANSI(s)
+
+	if Width(s) <= width {
+		return s
+	}
+
+	if width <= 1 {
+		return Truncate(s, width)
+	}
+
+	runes := []rune(s)
+
+	// The end gets the smaller half of the columns left by the ellipsis.
+	tailWidth := (width - 1) / 2
+	headWidth := width - 1 - tailWidth
+
+	head := 0
+
+	for used := 0; head < len(runes); head++ {
+		w := RuneWidth(runes[head])
+
+		if used+w > headWidth {
+			break
+		}
+
+		used += w
+	}
+
+	tail := len(runes)
+
+	for used := 0; tail > head; tail-- {
+		w := RuneWidth(runes[tail-1])
+
+		if used+w > tailWidth {
+			break
+		}
+
+		used += w
+	}
+
+	// A combining mark whose character was cut goes with it.
+	for tail < len(runes) && RuneWidth(runes[tail]) == 0 {
+		tail++
+	}
+
+	return string(runes[:head]) + Ellipsis + string(runes[tail:])
+}
+
+// Wrap breaks s into lines of at most width columns, between words where it
+// can. Words wider than a line are split. ANSI escape sequences take no room
+// and are kept with the text that follows them.
+func Wrap(s string, width int) []string {
+	if width <= 0 {
+		return []string{s}
+	}
+
+	var lines []string
+
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		used := 0
+
+		for _, word := range strings.Fields(paragraph) {
+			w := Width(word)
+
+			if used > 0 && used+1+w <= width {
+				line += " " + word
+				used += 1 + w
+				continue
+			}
+
+			if used > 0 {
+				lines = append(lines, line)
+				line, used = "", 0
+			}
+
+			for w > width {
+				part := cut(word, width)
+
+				// A single character wider than the line has a line of its
+				// own.
+				if len(part) == len(word) {
+					break
+				}
+
+				lines = append(lines, part)
+				word = word[len(part):]
+				w = Width(word)
+			}
+
+			line, used = word, w
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// cut returns the start of s that fits in width columns, with at least one
+// character so that wrapping always moves on.
+func cut(s string, width int) string {
+	used := 0
+
+	for i := 0; i < len(s); {
+		if n := escapeLength(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := RuneWidth(r)
+
+		if used+w > width && used > 0 {
+			return s[:i]
+		}
+
+		used += w
+		i += size
+	}
+
+	return s
+}
@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"日本語", 6},
+		{"ｈｌｓ", 6},
+		{"e\u0301te\u0301", 3},
+		{"a\u200bb", 2},
+		{"\033[31mred\033[0m", 3},
+		{"\033[1;33m日本\033[0m", 4},
+		{"🎬 live", 7},
+		{"tab\there", 7},
+	}
+
+	for _, tt := range tests {
+		if got := Width(tt.s); got != tt.want {
+			t.Errorf("Width(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+		left  string
+	}{
+		{"ab", 4, "ab  ", "  ab"},
+		{"日本", 5, "日本 ", " 日本"},
+		{"e\u0301", 3, "e\u0301  ", "  e\u0301"},
+		{"\033[31mab\033[0m", 3, "\033[31mab\033[0m ", " \033[31mab\033[0m"},
+		{"abcdef", 3, "abcdef", "abcdef"},
+		{"ab", 0, "ab", "ab"},
+	}
+
+	for _, tt := range tests {
+		if got := Pad(tt.s, tt.width); got != tt.want {
+			t.Errorf("Pad(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+
+		if got := PadLeft(tt.s, tt.width); got != tt.left {
+			t.Errorf("PadLeft(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.left)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abcdef", 10, "abcdef"},
+		{"abcdef", 6, "abcdef"},
+		{"abcdef", 4, "abc…"},
+		{"abcdef", 1, "…"},
+		{"abcdef", 0, ""},
+		{"abcdef", -1, ""},
+		{"", 0, ""},
+		{"日本語", 5, "日本…"},
+		{"日本語", 4, "日…"},
+		{"日本語", 2, "…"},
+		{"e\u0301e\u0301e\u0301", 2, "e\u0301…"},
+		{"\033[31mabcdef\033[0m", 4, "\033[31mabc…\033[0m"},
+		{"\033[31mab\033[0m", 2, "\033[31mab\033[0m"},
+	}
+
+	for _, tt := range tests {
+		got := Truncate(tt.s, tt.width)
+
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+
+		if tt.width >= 0 && Width(got) > tt.width {
+			t.Errorf("Truncate(%q, %d) is %d columns", tt.s, tt.width, Width(got))
+		}
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"http://cdn/live/v720.m3u8", 30, "http://cdn/live/v720.m3u8"},
+		{"http://cdn/live/v720.m3u8", 12, "http:/….m3u8"},
+		{"abcdef", 5, "ab…ef"},
+		{"abcdef", 4, "ab…f"},
+		{"abcdef", 2, "a…"},
+		{"abcdef", 1, "…"},
+		{"abcdef", 0, ""},
+		{"日本語です", 7, "日…す"},
+		{"日本語です", 4, "日…"},
+		{"abcde\u0301f", 4, "ab…f"},
+		{"ae\u0301bcde\u0301", 5, "ae\u0301…de\u0301"},
+		{"\033[31mabcdef\033[0m", 5, "ab…ef"},
+		{"\033[31mabc\033[0m", 5, "abc"},
+	}
+
+	for _, tt := range tests {
+		got := TruncateMiddle(tt.s, tt.width)
+
+		if got != tt.want {
+			t.Errorf("TruncateMiddle(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+
+		if Width(got) > tt.width {
+			t.Errorf("TruncateMiddle(%q, %d) is %d columns", tt.s, tt.width, Width(got))
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		width int
+		want  []string
+	}{
+		{"fits", "a short line", 20, []string{"a short line"}},
+		{"between words", "one two three four", 9, []string{"one two", "three", "four"}},
+		{"spaces collapse", "one   two  ", 20, []string{"one two"}},
+		{"paragraphs", "one\n\ntwo", 20, []string{"one", "", "two"}},
+		{"long word", "http://cdn.example.com/v1.m3u8", 10, []string{"http://cdn", ".example.c", "om/v1.m3u8"}},
+		{"long word after a word", "see abcdefghij", 6, []string{"see", "abcdef", "ghij"}},
+		{"wide runes", "日本語です", 5, []string{"日本", "語で", "す"}},
+		{"wide rune wider than a line", "日本", 1, []string{"日", "本"}},
+		{"combining marks", "e\u0301e\u0301e\u0301", 2, []string{"e\u0301e\u0301", "e\u0301"}},
+		{"colors", "\033[31mred\033[0m text", 4, []string{"\033[31mred\033[0m", "text"}},
+		{"no width", "one two", 0, []string{"one two"}},
+		{"empty", "", 10, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Wrap(tt.s, tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Wrap(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+			}
+		})
+	}
+}